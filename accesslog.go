@@ -0,0 +1,124 @@
+package tiny
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// LogFormatJSON logs each request as a JSON object, this is the default format.
+	LogFormatJSON LogFormat = "json"
+	// LogFormatCommon logs each request in Apache common log format.
+	LogFormatCommon LogFormat = "common"
+	// LogFormatCombined logs each request in Apache combined log format,
+	// which is the common log format plus the Referer and User-Agent headers.
+	LogFormatCombined LogFormat = "combined"
+
+	accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+)
+
+type (
+	// LogFormat is the format of the access logs.
+	LogFormat string
+
+	// accessLogEntry holds the information of a served request.
+	accessLogEntry struct {
+		Time       time.Time `json:"time"`
+		RemoteAddr string    `json:"remote_addr"`
+		Method     string    `json:"method"`
+		URI        string    `json:"uri"`
+		Proto      string    `json:"proto"`
+		Status     int       `json:"status"`
+		Size       int       `json:"size"`
+		Duration   float64   `json:"duration_ms"`
+		Referer    string    `json:"referer"`
+		UserAgent  string    `json:"user_agent"`
+	}
+
+	// accessLogResponseWriter records the status and the size of the response.
+	accessLogResponseWriter struct {
+		http.ResponseWriter
+		status int
+		size   int
+	}
+)
+
+// AccessLog provides middleware for logging the served requests into w in the given format.
+func AccessLog(w io.Writer, format LogFormat) func(http.Handler) http.Handler {
+	mu := sync.Mutex{}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			lw := &accessLogResponseWriter{ResponseWriter: rw}
+			h.ServeHTTP(lw, r)
+			if lw.status == 0 {
+				lw.status = http.StatusOK
+			}
+			entry := accessLogEntry{
+				Time:       start,
+				RemoteAddr: clientIP(r),
+				Method:     r.Method,
+				URI:        r.URL.RequestURI(),
+				Proto:      r.Proto,
+				Status:     lw.status,
+				Size:       lw.size,
+				Duration:   float64(time.Since(start).Microseconds()) / 1000,
+				Referer:    r.Referer(),
+				UserAgent:  r.UserAgent(),
+			}
+			b := entry.format(format)
+			mu.Lock()
+			defer mu.Unlock()
+			w.Write(b)
+		})
+	}
+}
+
+// format return the log line of the entry in the given format.
+func (e accessLogEntry) format(format LogFormat) []byte {
+	switch format {
+	case LogFormatCommon, LogFormatCombined:
+		size := "-"
+		if e.Size > 0 {
+			size = strconv.Itoa(e.Size)
+		}
+		line := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %s", e.RemoteAddr, e.Time.Format(accessLogTimeFormat), e.Method, e.URI, e.Proto, e.Status, size)
+		if format == LogFormatCombined {
+			line += fmt.Sprintf(" %q %q", e.Referer, e.UserAgent)
+		}
+		return []byte(line + "\n")
+	default:
+		b, err := json.Marshal(e)
+		if err != nil {
+			return []byte(fmt.Sprintf("{\"error\":%q}\n", err.Error()))
+		}
+		return append(b, '\n')
+	}
+}
+
+func (w *accessLogResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessLogResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *accessLogResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
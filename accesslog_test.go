@@ -0,0 +1,72 @@
+package tiny_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestAccessLog(t *testing.T) {
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/posts?page=2", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("Referer", "https://example.com/")
+		r.Header.Set("User-Agent", "test-agent/1.0")
+		return r
+	}
+	h := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write([]byte("hello"))
+	})
+	cases := []struct {
+		format tiny.LogFormat
+		want   string
+	}{
+		{
+			format: tiny.LogFormatCommon,
+			want:   `^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /posts\?page=2 HTTP/1\.1" 201 5\n$`,
+		},
+		{
+			format: tiny.LogFormatCombined,
+			want:   `^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /posts\?page=2 HTTP/1\.1" 201 5 "https://example\.com/" "test-agent/1\.0"\n$`,
+		},
+	}
+	for _, c := range cases {
+		buf := &bytes.Buffer{}
+		serve(tiny.AccessLog(buf, c.format)(h), newRequest())
+		if ok, _ := regexp.MatchString(c.want, buf.String()); !ok {
+			t.Errorf("format: %s, got log=%s, want log matches=%s", c.format, buf.String(), c.want)
+		}
+	}
+	for _, format := range []tiny.LogFormat{tiny.LogFormatJSON, ""} {
+		buf := &bytes.Buffer{}
+		serve(tiny.AccessLog(buf, format)(h), newRequest())
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("format: %s, got invalid json log=%s, err: %v", format, buf.String(), err)
+		}
+		want := map[string]interface{}{
+			"remote_addr": "10.0.0.1",
+			"method":      "GET",
+			"uri":         "/posts?page=2",
+			"proto":       "HTTP/1.1",
+			"status":      float64(201),
+			"size":        float64(5),
+			"referer":     "https://example.com/",
+			"user_agent":  "test-agent/1.0",
+		}
+		for k, v := range want {
+			if entry[k] != v {
+				t.Errorf("format: %s, field: %s, got value=%v, want value=%v", format, k, entry[k], v)
+			}
+		}
+		if _, ok := entry["time"]; !ok {
+			t.Errorf("format: %s, got no time field", format)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package tiny
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	assetHashLen = 8
+	// defaultAssetMaxAge is the max age of the assets without hash in their names,
+	// it's short so that the changes are picked up soon.
+	defaultAssetMaxAge = time.Hour
+	immutableMaxAge    = 365 * 24 * time.Hour
+)
+
+var (
+	// hashedNameRegex matches the file names contain a content hash, i.e: app.3f2a1b9c.js, app-3f2a1b9c.js.
+	hashedNameRegex = regexp.MustCompile(`(?i)[.-][0-9a-f]{8,}\.[^./]+$`)
+)
+
+type (
+	// assetDir is a directory of assets served under the prefix.
+	assetDir struct {
+		prefix string
+		dir    string
+		// urls map the asset names to their fingerprinted URLs,
+		// files map the fingerprinted names back to the asset names.
+		urls   map[string]string
+		files  map[string]string
+		hashes map[string]string
+	}
+)
+
+// setupAssets compute the content hashes of the asset files.
+func (site *Site) setupAssets() error {
+	for _, a := range site.assets {
+		a.prefix = strings.TrimSuffix("/"+strings.Trim(a.prefix, "/"), "/") + "/"
+		a.urls = make(map[string]string)
+		a.files = make(map[string]string)
+		a.hashes = make(map[string]string)
+		root := cleanPath(a.dir)
+		err := fs.WalkDir(site.fs, root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			b, err := fs.ReadFile(site.fs, p)
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256(b)
+			hash := hex.EncodeToString(sum[:])[:assetHashLen]
+			name := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+			hashed := name
+			if !hashedNameRegex.MatchString(name) {
+				ext := path.Ext(name)
+				hashed = fmt.Sprintf("%s.%s%s", strings.TrimSuffix(name, ext), hash, ext)
+			}
+			a.urls[name] = path.Join(a.prefix, hashed)
+			a.files[hashed] = name
+			a.hashes[name] = hash
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("invalid assets: %s, err: %w", a.dir, err)
+		}
+	}
+	return nil
+}
+
+// assetURL return the fingerprinted URL of the given asset, i.e: css/app.css -> /assets/css/app.3f2a1b9c.css.
+func (site *Site) assetURL(name string) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+	for _, a := range site.assets {
+		if u, ok := a.urls[name]; ok {
+			return u, nil
+		}
+		// the name may contain the prefix of the assets.
+		if u, ok := a.urls[strings.TrimPrefix(name, strings.Trim(a.prefix, "/")+"/")]; ok {
+			return u, nil
+		}
+	}
+	return "", fmt.Errorf("asset: %s not found", name)
+}
+
+// assetHandler serve the assets, the fingerprinted files are cached as immutable
+// while the others are cached shortly.
+func (site *Site) assetHandler(a *assetDir) http.Handler {
+	immutable := fmt.Sprintf("public, max-age=%d, immutable", int64(immutableMaxAge.Seconds()))
+	short := fmt.Sprintf("public, max-age=%d", int64(defaultAssetMaxAge.Seconds()))
+	return http.StripPrefix(a.prefix, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		cacheControl := short
+		if f, ok := a.files[name]; ok {
+			if hashedNameRegex.MatchString(name) {
+				cacheControl = immutable
+			}
+			name = f
+		}
+		hash, ok := a.hashes[name]
+		if !ok {
+			site.handleError(rw, r, "", NewError(http.StatusNotFound, "asset not found"))
+			return
+		}
+		rw.Header().Set("Cache-Control", cacheControl)
+		rw.Header().Set("ETag", `"`+hash+`"`)
+		if err := serveFile(rw, r, site.fs, path.Join(a.dir, name)); err != nil {
+			site.logger.Errorf("serve asset: %s, err: %v", name, err)
+			site.handleError(rw, r, "", err)
+		}
+	}))
+}
@@ -0,0 +1,91 @@
+package tiny_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestAssets(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+`, map[string]string{
+		"index.html":                       `[[asset "css/app.css"]]`,
+		"web/assets/css/app.css":           `body { color: red; }`,
+		"web/assets/js/vendor.3f2a1b9c.js": `console.log("vendor")`,
+	}, tiny.Assets("/assets/", "web/assets"))
+
+	// the asset URL is fingerprinted with the content hash.
+	u := serve(site, httptest.NewRequest(http.MethodGet, "/", nil)).Body.String()
+	if !regexp.MustCompile(`^/assets/css/app\.[0-9a-f]{8}\.css$`).MatchString(u) {
+		t.Fatalf("got asset url=%s, want fingerprinted url", u)
+	}
+	cases := []struct {
+		name         string
+		path         string
+		code         int
+		body         string
+		cacheControl string
+	}{
+		{
+			name:         "fingerprinted",
+			path:         u,
+			code:         http.StatusOK,
+			body:         `body { color: red; }`,
+			cacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name:         "hashed by bundler",
+			path:         "/assets/js/vendor.3f2a1b9c.js",
+			code:         http.StatusOK,
+			body:         `console.log("vendor")`,
+			cacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name:         "without hash",
+			path:         "/assets/css/app.css",
+			code:         http.StatusOK,
+			body:         `body { color: red; }`,
+			cacheControl: "public, max-age=3600",
+		},
+		{
+			name: "not found",
+			path: "/assets/css/missing.css",
+			code: http.StatusNotFound,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rw := serve(site, httptest.NewRequest(http.MethodGet, c.path, nil))
+			if rw.Code != c.code {
+				t.Fatalf("got code=%d, want code=%d", rw.Code, c.code)
+			}
+			if c.code != http.StatusOK {
+				return
+			}
+			if got := rw.Body.String(); got != c.body {
+				t.Errorf("got body=%s, want body=%s", got, c.body)
+			}
+			if got := rw.Header().Get("Cache-Control"); got != c.cacheControl {
+				t.Errorf("got Cache-Control=%s, want Cache-Control=%s", got, c.cacheControl)
+			}
+			if rw.Header().Get("ETag") == "" {
+				t.Errorf("got no ETag, want ETag")
+			}
+		})
+	}
+
+	// the content hash is used as ETag.
+	rw := serve(site, httptest.NewRequest(http.MethodGet, u, nil))
+	r := httptest.NewRequest(http.MethodGet, u, nil)
+	r.Header.Set("If-None-Match", rw.Header().Get("ETag"))
+	if rw := serve(site, r); rw.Code != http.StatusNotModified {
+		t.Errorf("got code=%d, want code=%d", rw.Code, http.StatusNotModified)
+	}
+}
@@ -0,0 +1,288 @@
+package tiny
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultCompressMinSize is the default minimum size of the responses to be compressed.
+	DefaultCompressMinSize = 1024
+)
+
+var (
+	// compressorPools are the pools of the supported encodings, in order of preference.
+	compressorPools = []struct {
+		encoding string
+		pool     *sync.Pool
+	}{
+		{
+			encoding: "gzip",
+			pool: &sync.Pool{
+				New: func() interface{} {
+					return gzip.NewWriter(nil)
+				},
+			},
+		},
+		{
+			encoding: "deflate",
+			pool: &sync.Pool{
+				New: func() interface{} {
+					w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+					return w
+				},
+			},
+		},
+	}
+
+	// DefaultCompressTypes are the content types to be compressed by default, see CompressTypes.
+	DefaultCompressTypes = []string{
+		"text/",
+		"application/json",
+		"application/javascript",
+		"application/xml",
+		"image/svg+xml",
+		"font/ttf",
+		"font/otf",
+		"+json",
+		"+xml",
+	}
+)
+
+type (
+	// CompressOption customizes the Compress middleware.
+	CompressOption func(cfg *compressConfig)
+
+	compressConfig struct {
+		minSize int
+		types   []string
+	}
+
+	// compressor is implemented by both gzip.Writer and flate.Writer.
+	compressor interface {
+		io.WriteCloser
+		Flush() error
+		Reset(w io.Writer)
+	}
+
+	// compressResponseWriter streams the response body through a gzip or deflate writer.
+	// The body is buffered until it reaches the min size, so that small responses
+	// are sent uncompressed. The compressor is created lazily, so responses without body are not compressed.
+	compressResponseWriter struct {
+		http.ResponseWriter
+		cfg         *compressConfig
+		encoding    string
+		pool        *sync.Pool
+		cw          compressor
+		status      int
+		buf         []byte
+		started     bool
+		passthrough bool
+	}
+)
+
+// CompressMinSize set the minimum size in bytes of the responses to be compressed,
+// default to DefaultCompressMinSize. Use 0 to compress all responses.
+func CompressMinSize(n int) CompressOption {
+	return func(cfg *compressConfig) {
+		cfg.minSize = n
+	}
+}
+
+// CompressTypes set the content types to be compressed, default to DefaultCompressTypes.
+// A type ends with "/" matches all its subtypes, i.e: text/, a type starts with "+"
+// matches the structured syntax suffix, i.e: +json for application/ld+json.
+func CompressTypes(types ...string) CompressOption {
+	return func(cfg *compressConfig) {
+		cfg.types = types
+	}
+}
+
+// Compress provides middleware for compressing responses with gzip or deflate
+// for clients which accept it, gzip is preferred if both are accepted. The response is streamed through
+// the compressor without buffering the whole body, hence it's safe for large files.
+// Only the responses of the allowed content types, which are at least the min size are compressed,
+// others such as images, videos and small responses are sent as is.
+// Since the size is unknown until the body is written, the body is buffered until it reaches the min size.
+// Range requests are served as is, so that seeking and resumable downloads keep working.
+func Compress(opts ...CompressOption) func(http.Handler) http.Handler {
+	cfg := &compressConfig{
+		minSize: DefaultCompressMinSize,
+		types:   DefaultCompressTypes,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Add("Vary", "Accept-Encoding")
+			// ranges of the compressed content are not supported, serve the partial content uncompressed instead.
+			if r.Header.Get("Range") != "" {
+				h.ServeHTTP(rw, r)
+				return
+			}
+			for _, c := range compressorPools {
+				if !acceptsEncoding(r, c.encoding) {
+					continue
+				}
+				cw := &compressResponseWriter{ResponseWriter: rw, cfg: cfg, encoding: c.encoding, pool: c.pool}
+				defer cw.Close()
+				h.ServeHTTP(cw, r)
+				return
+			}
+			h.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// isCompressible report whether the given content type is one of the allowed types.
+func (cfg *compressConfig) isCompressible(contentType string) bool {
+	ct := contentType
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	ct = strings.ToLower(strings.TrimSpace(ct))
+	for _, t := range cfg.types {
+		t = strings.ToLower(t)
+		switch {
+		case strings.HasSuffix(t, "/") && strings.HasPrefix(ct, t):
+			return true
+		case strings.HasPrefix(t, "+") && strings.HasSuffix(ct, t):
+			return true
+		case ct == t:
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	h := w.Header()
+	if code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent || (code >= 100 && code < 200) || h.Get("Content-Encoding") != "" {
+		w.start(false)
+		return
+	}
+	if ct := h.Get("Content-Type"); ct != "" && !w.cfg.isCompressible(ct) {
+		w.start(false)
+		return
+	}
+	if n, err := strconv.Atoi(h.Get("Content-Length")); err == nil && n < w.cfg.minSize {
+		w.start(false)
+	}
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.started {
+		return w.write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < w.cfg.minSize {
+		return len(b), nil
+	}
+	if err := w.decide(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// decide start the response compressed or not base on its content type,
+// and write the buffered data.
+func (w *compressResponseWriter) decide() error {
+	h := w.Header()
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		// detect the content type from the uncompressed data.
+		ct = http.DetectContentType(w.buf)
+		h.Set("Content-Type", ct)
+	}
+	w.start(w.cfg.isCompressible(ct))
+	buf := w.buf
+	w.buf = nil
+	_, err := w.write(buf)
+	return err
+}
+
+// start write the header to the client, the response is compressed if compress is true.
+func (w *compressResponseWriter) start(compress bool) {
+	w.started = true
+	if compress {
+		h := w.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+	} else {
+		w.passthrough = true
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *compressResponseWriter) write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if w.cw == nil {
+		w.cw = w.pool.Get().(compressor)
+		w.cw.Reset(w.ResponseWriter)
+	}
+	return w.cw.Write(b)
+}
+
+// Flush flushes the compressed data written so far to the client.
+func (w *compressResponseWriter) Flush() {
+	if !w.started && w.status != 0 {
+		w.decide()
+	}
+	if w.cw != nil {
+		w.cw.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack allows the handler to take over the connection, i.e: for websocket.
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Close writes the small responses as is, flushes the remaining compressed data
+// and releases the compressor.
+func (w *compressResponseWriter) Close() error {
+	if !w.started && w.status != 0 {
+		if w.Header().Get("Content-Type") == "" && len(w.buf) > 0 {
+			w.Header().Set("Content-Type", http.DetectContentType(w.buf))
+		}
+		w.start(false)
+		buf := w.buf
+		w.buf = nil
+		if _, err := w.write(buf); err != nil {
+			return err
+		}
+	}
+	if w.cw == nil {
+		return nil
+	}
+	err := w.cw.Close()
+	w.pool.Put(w.cw)
+	w.cw = nil
+	return err
+}
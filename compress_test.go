@@ -0,0 +1,312 @@
+package tiny_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+type (
+	// countingWriter counts number of writes to the underlying writer.
+	countingWriter struct {
+		*httptest.ResponseRecorder
+		writes int
+	}
+)
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestCompressStaticFile(t *testing.T) {
+	// random-ish content so that it doesn't compress into a single chunk.
+	content := &strings.Builder{}
+	for i := 0; content.Len() < 4<<20; i++ {
+		fmt.Fprintf(content, "line %d %x\n", i, i*i*7919)
+	}
+	site := newTestSite(t, `
+pages:
+  static:
+    path: /static/
+    data: file://static/
+static_site:
+  enable: true
+  allowed_pages: ["^/$"]
+  output:
+    root_dir: public
+  request:
+    host: http://localhost
+`, map[string]string{
+		"static/large.txt": content.String(),
+		"public/.keep":     "",
+	})
+	h := tiny.Compress()(site)
+	r := httptest.NewRequest(http.MethodGet, "/static/large.txt", nil)
+	r.Header.Set("Accept-Encoding", "gzip, deflate")
+	rw := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(rw, r)
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got code=%d, want code=%d", rw.Code, http.StatusOK)
+	}
+	if got := rw.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("got Content-Encoding=%s, want gzip", got)
+	}
+	if rw.Header().Get("Content-Length") != "" {
+		t.Errorf("got Content-Length=%s, want no Content-Length", rw.Header().Get("Content-Length"))
+	}
+	if rw.writes < 2 {
+		t.Errorf("got writes=%d, want response to be streamed in multiple writes", rw.writes)
+	}
+	gz, err := gzip.NewReader(rw.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, []byte(content.String())) {
+		t.Errorf("got decompressed length=%d, want length=%d", len(b), content.Len())
+	}
+	// static assets are not captured by the static generator.
+	if _, err := os.Stat("public/static/large.txt"); !os.IsNotExist(err) {
+		t.Errorf("got static asset written by the generator, want not written")
+	}
+	if _, err := os.Stat("public/large.txt.html"); !os.IsNotExist(err) {
+		t.Errorf("got static asset written by the generator, want not written")
+	}
+}
+
+func TestCompressNotAccepted(t *testing.T) {
+	body := strings.Repeat("hello ", 1000)
+	h := tiny.Compress()(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Write([]byte(body))
+	}))
+	for _, accept := range []string{"gzip;q=0, br", "gzip;q=0, deflate;q=0, *", "*, gzip;q=0, deflate;q=0", "*;q=0"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", accept)
+		rw := serve(h, r)
+		if rw.Header().Get("Content-Encoding") != "" || rw.Body.String() != body {
+			t.Errorf("Accept-Encoding: %s, got Content-Encoding=%s, want uncompressed", accept, rw.Header().Get("Content-Encoding"))
+		}
+	}
+}
+
+func TestCompressDeflate(t *testing.T) {
+	body := strings.Repeat("hello ", 1000)
+	h := tiny.Compress()(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Write([]byte(body))
+	}))
+	cases := map[string]string{
+		"deflate":            "deflate",
+		"gzip, deflate":      "gzip",
+		"gzip;q=0, deflate":  "deflate",
+		"gzip;q=0, *":        "deflate",
+		"deflate;q=0, gzip":  "gzip",
+		"deflate, gzip;q=.5": "gzip",
+	}
+	for accept, want := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", accept)
+		rw := serve(h, r)
+		if got := rw.Header().Get("Content-Encoding"); got != want {
+			t.Errorf("Accept-Encoding: %s, got Content-Encoding=%s, want %s", accept, got, want)
+			continue
+		}
+		var zr io.Reader
+		if want == "gzip" {
+			gz, err := gzip.NewReader(rw.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zr = gz
+		} else {
+			zr = flate.NewReader(rw.Body)
+		}
+		b, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != body {
+			t.Errorf("Accept-Encoding: %s, got decompressed length=%d, want length=%d", accept, len(b), len(body))
+		}
+	}
+}
+
+func TestCompressSite(t *testing.T) {
+	page := "<!DOCTYPE html><html><body>" + strings.Repeat("<p>hello tiny</p>", 200) + "</body></html>"
+	config := `
+compress: %v
+pages:
+  index:
+    path: /
+    components: [index.html]
+  small:
+    path: /small
+    components: [small.html]
+  static:
+    path: /static/
+    data: file://static/
+`
+	files := map[string]string{
+		"index.html":     page,
+		"small.html":     `<p>hi</p>`,
+		"static/img.png": "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 4096),
+	}
+	get := func(h http.Handler, pth string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, pth, nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		return serve(h, r)
+	}
+	cases := []struct {
+		name     string
+		compress bool
+		opts     []tiny.Option
+	}{
+		{name: "yaml", compress: true},
+		{name: "option", opts: []tiny.Option{tiny.Compression()}},
+	}
+	for _, c := range cases {
+		name := c.name
+		site := newTestSite(t, fmt.Sprintf(config, c.compress), files, c.opts...)
+		rw := get(site, "/")
+		if got := rw.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("%s: got Content-Encoding=%s, want gzip", name, got)
+		}
+		if got := rw.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("%s: got Vary=%s, want Accept-Encoding", name, got)
+		}
+		gz, err := gzip.NewReader(rw.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != page {
+			t.Errorf("%s: got decompressed body=%s, want body=%s", name, b, page)
+		}
+		// small responses are not compressed.
+		rw = get(site, "/small")
+		if rw.Header().Get("Content-Encoding") != "" || rw.Body.String() != `<p>hi</p>` {
+			t.Errorf("%s: got Content-Encoding=%s, body=%s, want uncompressed small response", name, rw.Header().Get("Content-Encoding"), rw.Body.String())
+		}
+		// already compressed content is not compressed again.
+		rw = get(site, "/static/img.png")
+		if rw.Header().Get("Content-Encoding") != "" || rw.Body.Len() != len(files["static/img.png"]) {
+			t.Errorf("%s: got Content-Encoding=%s, length=%d, want uncompressed image", name, rw.Header().Get("Content-Encoding"), rw.Body.Len())
+		}
+	}
+	// compression is disabled by default.
+	site := newTestSite(t, fmt.Sprintf(config, false), files)
+	if got := get(site, "/").Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("got Content-Encoding=%s, want no compression by default", got)
+	}
+}
+
+func TestCompressRange(t *testing.T) {
+	files := map[string]string{
+		"static/video.mp4": strings.Repeat("v", 4000),
+		"static/large.txt": strings.Repeat("hello ", 1000),
+	}
+	site := newTestSite(t, `
+pages:
+  static:
+    path: /static/
+    data: file://static/
+`, files)
+	h := tiny.Compress()(site)
+	for _, name := range []string{"video.mp4", "large.txt"} {
+		r := httptest.NewRequest(http.MethodGet, "/static/"+name, nil)
+		r.Header.Set("Accept-Encoding", "gzip, deflate")
+		r.Header.Set("Range", "bytes=0-99")
+		rw := serve(h, r)
+		if rw.Code != http.StatusPartialContent {
+			t.Errorf("%s: got code=%d, want code=%d", name, rw.Code, http.StatusPartialContent)
+		}
+		if rw.Header().Get("Content-Encoding") != "" || rw.Body.String() != files["static/"+name][:100] {
+			t.Errorf("%s: got Content-Encoding=%s, length=%d, want uncompressed partial content", name, rw.Header().Get("Content-Encoding"), rw.Body.Len())
+		}
+	}
+}
+
+func TestCompressMinSize(t *testing.T) {
+	h := tiny.Compress(tiny.CompressMinSize(0))(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("hi"))
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rw := serve(h, r)
+	if got := rw.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("got Content-Encoding=%s, want gzip", got)
+	}
+}
+
+func TestCompressTypesAndSize(t *testing.T) {
+	large := strings.Repeat("hello tiny ", 200)
+	cases := []struct {
+		name        string
+		opts        []tiny.CompressOption
+		contentType string
+		writes      []string
+		compressed  bool
+	}{
+		{name: "tiny text", contentType: "text/plain", writes: []string{"hi"}, compressed: false},
+		{name: "large text", contentType: "text/html; charset=utf-8", writes: []string{large}, compressed: true},
+		{name: "threshold crossed by many writes", contentType: "application/json", writes: strings.SplitAfter(large, " "), compressed: true},
+		{name: "structured suffix", contentType: "application/ld+json", writes: []string{large}, compressed: true},
+		{name: "svg", contentType: "image/svg+xml", writes: []string{large}, compressed: true},
+		{name: "not allowed type", contentType: "application/octet-stream", writes: []string{large}, compressed: false},
+		{name: "detected type", writes: []string{large}, compressed: true},
+		{name: "custom types", opts: []tiny.CompressOption{tiny.CompressTypes("application/x-custom")}, contentType: "application/x-custom", writes: []string{large}, compressed: true},
+		{name: "custom types exclude text", opts: []tiny.CompressOption{tiny.CompressTypes("application/x-custom")}, contentType: "text/plain", writes: []string{large}, compressed: false},
+		{name: "custom min size", opts: []tiny.CompressOption{tiny.CompressMinSize(len(large) + 1)}, contentType: "text/plain", writes: []string{large}, compressed: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := tiny.Compress(c.opts...)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				if c.contentType != "" {
+					rw.Header().Set("Content-Type", c.contentType)
+				}
+				for _, w := range c.writes {
+					rw.Write([]byte(w))
+				}
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			rw := serve(h, r)
+			want := strings.Join(c.writes, "")
+			body := rw.Body.String()
+			if got := rw.Header().Get("Content-Encoding") == "gzip"; got != c.compressed {
+				t.Fatalf("got compressed=%v, want compressed=%v", got, c.compressed)
+			}
+			if c.compressed {
+				gz, err := gzip.NewReader(rw.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := io.ReadAll(gz)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = string(b)
+			}
+			if body != want {
+				t.Errorf("got body length=%d, want length=%d", len(body), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package tiny
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+)
+
+type (
+	// ContentStore provides the content of the site by key, i.e: the config, layouts, components and data files.
+	// The keys are the slash-separated paths of the files as they are referred in the config, i.e: web/index.html,
+	// hence the site can be served from any backend such as a database or S3.
+	// Get must return an error wraps fs.ErrNotExist if the key doesn't exist.
+	// Directories are not supported, hence static dirs and assets require a fs.FS, see WithFS.
+	ContentStore interface {
+		Get(key string) ([]byte, error)
+	}
+
+	// MemoryStore is an in-memory ContentStore, it's safe for concurrent use.
+	MemoryStore struct {
+		mu      sync.RWMutex
+		content map[string][]byte
+	}
+
+	// storeFS is a read-only fs.FS backed by a ContentStore.
+	storeFS struct {
+		store ContentStore
+	}
+
+	storeFile struct {
+		*bytes.Reader
+		info storeFileInfo
+	}
+
+	storeFileInfo struct {
+		name string
+		size int64
+	}
+)
+
+// NewMemoryStore return a new in-memory ContentStore with the given content by key.
+func NewMemoryStore(content map[string]string) *MemoryStore {
+	s := &MemoryStore{
+		content: make(map[string][]byte, len(content)),
+	}
+	for k, v := range content {
+		s.content[cleanPath(k)] = []byte(v)
+	}
+	return s
+}
+
+// Get return the content of the key.
+func (s *MemoryStore) Get(key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.content[cleanPath(key)]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return b, nil
+}
+
+// Set set the content of the key, the change takes effect on the next reload of the templates, see Site.Reload.
+func (s *MemoryStore) Set(key string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content[cleanPath(key)] = []byte(value)
+}
+
+func (fsys storeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	b, err := fsys.store.Get(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = fs.ErrNotExist
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &storeFile{
+		Reader: bytes.NewReader(b),
+		info:   storeFileInfo{name: path.Base(name), size: int64(len(b))},
+	}, nil
+}
+
+func (f *storeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *storeFile) Close() error               { return nil }
+
+func (fi storeFileInfo) Name() string       { return fi.name }
+func (fi storeFileInfo) Size() int64        { return fi.size }
+func (fi storeFileInfo) Mode() fs.FileMode  { return 0444 }
+func (fi storeFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi storeFileInfo) IsDir() bool        { return false }
+func (fi storeFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,82 @@
+package tiny_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestContentStore(t *testing.T) {
+	// make sure nothing is read from the working directory.
+	writeTestFiles(t, map[string]string{})
+	store := tiny.NewMemoryStore(map[string]string{
+		"site.yml": `
+reload: true
+layouts:
+  main: [web/main.html]
+pages:
+  index:
+    path: /
+    layout: main
+    components: [web/index.html]
+  posts:
+    path: /posts
+    layout: main
+    components: [web/posts.html]
+    data: file://data/posts.json
+    data_type: json
+  robots:
+    path: /robots.txt
+    data: file://web/robots.txt
+`,
+		"web/main.html":   `<main>[[template "content" .]]</main>`,
+		"web/index.html":  `[[define "content"]]index[[end]]`,
+		"web/posts.html":  `[[define "content"]][[range .Data.posts]][[.]] [[end]][[end]]`,
+		"data/posts.json": `{"posts": ["hello", "world"]}`,
+		"web/robots.txt":  "User-agent: *",
+	})
+	site, err := tiny.NewSiteFromFile("site.yml", tiny.WithContentStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		path string
+		body string
+	}{
+		{path: "/", body: "<main>index</main>"},
+		{path: "/posts", body: "<main>hello world </main>"},
+		{path: "/robots.txt", body: "User-agent: *"},
+	}
+	for _, c := range cases {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if rw.Code != http.StatusOK {
+			t.Errorf("path=%s, got code=%d, want code=%d", c.path, rw.Code, http.StatusOK)
+		}
+		if got := rw.Body.String(); got != c.body {
+			t.Errorf("path=%s, got body=%s, want body=%s", c.path, got, c.body)
+		}
+	}
+	// the templates are read from the store on reload.
+	store.Set("web/index.html", `[[define "content"]]updated[[end]]`)
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got, want := rw.Body.String(), "<main>updated</main>"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestContentStoreMissingComponent(t *testing.T) {
+	writeTestFiles(t, map[string]string{})
+	store := tiny.NewMemoryStore(map[string]string{
+		"site.yml": `
+pages:
+  index:
+    path: /
+    components: [web/missing.html]
+`,
+	})
+	if _, err := tiny.NewSiteFromFile("site.yml", tiny.WithContentStore(store)); err == nil {
+		t.Errorf("got err=nil, want error for missing component")
+	}
+}
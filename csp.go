@@ -0,0 +1,64 @@
+package tiny
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/pthethanh/tiny/funcs"
+)
+
+const (
+	// CSPNoncePlaceholder is replaced by the nonce of the request in the Content-Security-Policy headers,
+	// i.e: script-src 'self' 'nonce-{nonce}'.
+	CSPNoncePlaceholder = "{nonce}"
+)
+
+var (
+	cspHeaders = []string{"Content-Security-Policy", "Content-Security-Policy-Report-Only"}
+)
+
+type cspNonceKey struct{}
+
+// withCSPNonce return a request with a new CSP nonce attached to its context, the existing nonce is kept
+// so that the nonce is the same for all pages rendered in the request, i.e: error pages.
+func withCSPNonce(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(cspNonceKey{}).(string); ok {
+		return r
+	}
+	nonce, err := funcs.Nonce()
+	if err != nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), cspNonceKey{}, nonce))
+}
+
+// withoutCSPNonce return a request without CSP nonce, i.e: for the pages rendered by the static generator,
+// since the nonce would be frozen into the generated files and reused by every visitor.
+func withoutCSPNonce(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), cspNonceKey{}, ""))
+}
+
+// cspNonce return the CSP nonce of the request if any.
+func cspNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(cspNonceKey{}).(string)
+	return nonce
+}
+
+// setCSPNonce replace the nonce placeholder of the CSP headers with the nonce of the request.
+// The headers are left untouched if the request has no nonce, i.e: static files.
+// Responses carrying a nonce must not be cached, since the nonce must be unique per response.
+func setCSPNonce(h http.Header, r *http.Request) {
+	nonce := cspNonce(r)
+	if nonce == "" {
+		return
+	}
+	for _, k := range cspHeaders {
+		v := h.Get(k)
+		if !strings.Contains(v, CSPNoncePlaceholder) {
+			continue
+		}
+		h.Set(k, strings.ReplaceAll(v, CSPNoncePlaceholder, nonce))
+		h.Set("Cache-Control", "no-store")
+	}
+}
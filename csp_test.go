@@ -0,0 +1,102 @@
+package tiny_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestCSPNonce(t *testing.T) {
+	site := newTestSite(t, `
+headers:
+  Content-Security-Policy: "default-src 'self'; script-src 'self' 'nonce-{nonce}'"
+pages:
+  index:
+    path: /
+    components: [index.html]
+  500:
+    path: /500
+    components: [500.html]
+`, map[string]string{
+		"index.html": `<script nonce="[[.CSPNonce]]">console.log("hi")</script>`,
+		"500.html":   `<script nonce="[[.CSPNonce]]"></script>`,
+	})
+	re := regexp.MustCompile(`'nonce-([^']+)'`)
+	nonces := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+		m := re.FindStringSubmatch(rw.Header().Get("Content-Security-Policy"))
+		if m == nil || m[1] == "" {
+			t.Fatalf("got header=%s, want a nonce", rw.Header().Get("Content-Security-Policy"))
+		}
+		if got, want := rw.Body.String(), `<script nonce="`+m[1]+`">console.log("hi")</script>`; got != want {
+			t.Errorf("got body=%s, want body=%s", got, want)
+		}
+		nonces[m[1]] = true
+	}
+	if len(nonces) != 2 {
+		t.Errorf("got nonces=%v, want a new nonce per request", nonces)
+	}
+	// the error page of the request uses the same nonce.
+	site.SetDataHandler("index", func(rw http.ResponseWriter, r *http.Request) interface{} {
+		return errors.New("boom")
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+	m := re.FindStringSubmatch(rw.Header().Get("Content-Security-Policy"))
+	if m == nil {
+		t.Fatalf("got header=%s, want a nonce", rw.Header().Get("Content-Security-Policy"))
+	}
+	if got, want := rw.Body.String(), `<script nonce="`+m[1]+`"></script>`; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestCSPNonceCacheAndStatic(t *testing.T) {
+	csp := "script-src 'self' 'nonce-{nonce}'"
+	site := newTestSite(t, `
+page_max_age: 1h
+headers:
+  Content-Security-Policy: "`+csp+`"
+pages:
+  index:
+    path: /
+    components: [index.html]
+  logo:
+    path: /logo.png
+    data: file://logo.png
+static_site:
+  enable: true
+  allowed_pages: ["^/$"]
+  output:
+    root_dir: public
+`, map[string]string{
+		"index.html":   `<script nonce="[[.CSPNonce]]"></script>`,
+		"logo.png":     `logo`,
+		"public/.keep": ``,
+	})
+	// responses carrying a nonce are not cached.
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rw.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("got Cache-Control=%s, want no-store", got)
+	}
+	// static files have no nonce, the header is left untouched.
+	rw = serve(site, httptest.NewRequest(http.MethodGet, "/logo.png", nil))
+	if got := rw.Header().Get("Content-Security-Policy"); got != csp {
+		t.Errorf("got header=%s, want header=%s", got, csp)
+	}
+	// the generated pages have no nonce.
+	srv := httptest.NewServer(site)
+	defer srv.Close()
+	site.StaticSite.Request.Host = srv.URL
+	site.StaticSite.Request.Paths = []string{"/"}
+	if err := site.GenerateStaticSite(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(filepath.Join("public", "index.html")); err != nil || string(b) != `<script nonce=""></script>` {
+		t.Errorf("got page=%s, err=%v, want page without nonce", b, err)
+	}
+}
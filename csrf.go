@@ -0,0 +1,79 @@
+package tiny
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/pthethanh/tiny/funcs"
+)
+
+const (
+	// CSRFCookie is the name of the cookie holds the CSRF token.
+	CSRFCookie = "csrf_token"
+	// CSRFField is the name of the form field carries the CSRF token of the form submissions.
+	CSRFField = "csrf_token"
+	// CSRFHeader is the header carries the CSRF token of the AJAX requests.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+type csrfTokenKey struct{}
+
+// CSRF provides middleware for protecting the unsafe requests from cross-site request forgery
+// using the double submit cookie pattern: a random token is issued in a cookie and must be submitted
+// via the csrf_token form field or the X-CSRF-Token header of the unsafe requests, i.e: POST.
+// Requests with a missing or mismatched token are rejected with 403.
+// The token is available to the templates via `.CSRFToken` of PageData, i.e:
+//
+//	<input type="hidden" name="csrf_token" value="[[.CSRFToken]]">
+func CSRF() func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			token := ""
+			if ck, err := r.Cookie(CSRFCookie); err == nil {
+				token = ck.Value
+			}
+			if !isSafeMethod(r.Method) {
+				submitted := r.Header.Get(CSRFHeader)
+				if submitted == "" {
+					submitted = r.PostFormValue(CSRFField)
+				}
+				if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(submitted)) != 1 {
+					statusError(rw, r, http.StatusForbidden)
+					return
+				}
+			}
+			if token == "" {
+				t, err := funcs.Nonce()
+				if err != nil {
+					http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				token = t
+				http.SetCookie(rw, &http.Cookie{
+					Name:     CSRFCookie,
+					Value:    token,
+					Path:     "/",
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+			h.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), csrfTokenKey{}, token)))
+		})
+	}
+}
+
+// csrfToken return the CSRF token of the request if the CSRF middleware is enabled.
+func csrfToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfTokenKey{}).(string)
+	return token
+}
+
+// isSafeMethod report whether the method is safe, i.e: it doesn't change the state of the server.
+func isSafeMethod(m string) bool {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package tiny_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestCSRF(t *testing.T) {
+	config := `
+csrf: %v
+pages:
+  contact:
+    path: /contact
+    methods: [GET, POST]
+    components: [contact.html]
+`
+	files := map[string]string{
+		"contact.html": `[[.CSRFToken]]`,
+	}
+	check := func(t *testing.T, site *tiny.Site) {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, "/contact", nil))
+		cookies := rw.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != tiny.CSRFCookie || cookies[0].Value == "" {
+			t.Fatalf("got cookies=%v, want CSRF cookie", cookies)
+		}
+		token := cookies[0].Value
+		if got := rw.Body.String(); got != token {
+			t.Errorf("got token in template=%s, want token=%s", got, token)
+		}
+		post := func(field, header string) *httptest.ResponseRecorder {
+			form := url.Values{"message": {"hello"}}
+			if field != "" {
+				form.Set(tiny.CSRFField, field)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/contact", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if header != "" {
+				r.Header.Set(tiny.CSRFHeader, header)
+			}
+			r.AddCookie(cookies[0])
+			return serve(site, r)
+		}
+		cases := []struct {
+			name   string
+			field  string
+			header string
+			code   int
+		}{
+			{name: "valid form", field: token, code: http.StatusOK},
+			{name: "valid header", header: token, code: http.StatusOK},
+			{name: "missing token", code: http.StatusForbidden},
+			{name: "forged token", field: "forged" + token, code: http.StatusForbidden},
+		}
+		for _, c := range cases {
+			rw := post(c.field, c.header)
+			if rw.Code != c.code {
+				t.Errorf("%s: got code=%d, want code=%d", c.name, rw.Code, c.code)
+			}
+			if c.code == http.StatusOK && rw.Body.String() != token {
+				t.Errorf("%s: got token=%s, want the same token=%s", c.name, rw.Body.String(), token)
+			}
+		}
+		// submissions without the cookie are rejected.
+		form := url.Values{tiny.CSRFField: {token}}
+		r := httptest.NewRequest(http.MethodPost, "/contact", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if rw := serve(site, r); rw.Code != http.StatusForbidden {
+			t.Errorf("no cookie: got code=%d, want code=%d", rw.Code, http.StatusForbidden)
+		}
+	}
+	t.Run("config", func(t *testing.T) {
+		check(t, newTestSite(t, strings.Replace(config, "%v", "true", 1), files))
+	})
+	t.Run("option", func(t *testing.T) {
+		check(t, newTestSite(t, strings.Replace(config, "%v", "false", 1), files, tiny.CSRFProtection()))
+	})
+	t.Run("disabled", func(t *testing.T) {
+		site := newTestSite(t, strings.Replace(config, "%v", "false", 1), files)
+		rw := serve(site, httptest.NewRequest(http.MethodPost, "/contact", nil))
+		if rw.Code != http.StatusOK || len(rw.Result().Cookies()) != 0 {
+			t.Errorf("got code=%d, cookies=%v, want code=200 without cookie", rw.Code, rw.Result().Cookies())
+		}
+	})
+}
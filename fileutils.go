@@ -1,9 +1,13 @@
 package tiny
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 )
 
@@ -102,3 +106,55 @@ func copySymLink(source, dest string) error {
 	}
 	return os.Symlink(link, dest)
 }
+
+// osFS is a file system reads files directly via the os package,
+// the same as os.DirFS(".") except that absolute and parent paths are allowed.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+// cleanPath clean the path for opening from a fs.FS, i.e: ./web/index.html -> web/index.html.
+func cleanPath(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// httpDir return the http.FileSystem of the given directory of the file system.
+func httpDir(fsys fs.FS, dir string) (http.FileSystem, error) {
+	if _, ok := fsys.(osFS); ok {
+		return http.Dir(dir), nil
+	}
+	sub, err := fs.Sub(fsys, cleanPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	return http.FS(sub), nil
+}
+
+// serveFile serve the given file of the file system.
+func serveFile(rw http.ResponseWriter, r *http.Request, fsys fs.FS, name string) error {
+	if _, ok := fsys.(osFS); ok {
+		http.ServeFile(rw, r, name)
+		return nil
+	}
+	f, err := fsys.Open(cleanPath(name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		rs = bytes.NewReader(b)
+	}
+	http.ServeContent(rw, r, fi.Name(), fi.ModTime(), rs)
+	return nil
+}
@@ -0,0 +1,322 @@
+package funcs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// CollectionFuncMap return collection func map.
+func CollectionFuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"related":  Related,
+		"where":    Where,
+		"reject":   Reject,
+		"at":       At,
+		"seq":      Seq,
+		"until":    UntilN,
+		"find_by":  FindBy,
+		"index_by": IndexBy,
+		"count_by": CountBy,
+		"tally":    Tally,
+		"by_count": ByCount,
+	}
+}
+
+const (
+	// maxSeqLen limits the length of the sequences to prevent abuse.
+	maxSeqLen = 10000
+)
+
+// Seq return the integers from start to end inclusive, i.e: seq 1 3 -> [1 2 3].
+// The sequence is descending if start is greater than end, i.e: seq 3 1 -> [3 2 1].
+func Seq(start, end int) ([]int, error) {
+	step, n := 1, end-start+1
+	if start > end {
+		step, n = -1, start-end+1
+	}
+	if n > maxSeqLen {
+		return nil, fmt.Errorf("seq: length %d exceeds the limit %d", n, maxSeqLen)
+	}
+	rs := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		rs = append(rs, start+i*step)
+	}
+	return rs, nil
+}
+
+// UntilN return the integers from 0 to n-1, i.e: until 3 -> [0 1 2].
+// It's empty if n is not positive.
+func UntilN(n int) ([]int, error) {
+	if n <= 0 {
+		return []int{}, nil
+	}
+	return Seq(0, n-1)
+}
+
+// At return the element at index i of the slice, array or string, or the default (nil) if it's out of range.
+// Negative indices count from the end, i.e: at .Items -1 return the last item.
+// The element of a string is its character at i.
+func At(items interface{}, i int, df ...interface{}) interface{} {
+	var d interface{}
+	if len(df) > 0 {
+		d = df[0]
+	}
+	v, isNil := indirect(reflect.ValueOf(items))
+	if isNil || !v.IsValid() {
+		return d
+	}
+	switch v.Kind() {
+	case reflect.String:
+		rs := []rune(v.String())
+		if i < 0 {
+			i += len(rs)
+		}
+		if i < 0 || i >= len(rs) {
+			return d
+		}
+		return string(rs[i])
+	case reflect.Slice, reflect.Array:
+		if i < 0 {
+			i += v.Len()
+		}
+		if i < 0 || i >= v.Len() {
+			return d
+		}
+		return v.Index(i).Interface()
+	}
+	return d
+}
+
+// Where return the items whose field satisfies the comparison with the given value.
+// Supported operators: eq, ne, gt, ge, lt, le, contains.
+// Map items without the field don't match, hence they are kept by Reject.
+func Where(fieldName string, op string, value interface{}, items interface{}) ([]interface{}, error) {
+	return filter(fieldName, op, value, items, true)
+}
+
+// Reject is the inverse of Where, it returns the items whose field doesn't satisfy the comparison.
+func Reject(fieldName string, op string, value interface{}, items interface{}) ([]interface{}, error) {
+	return filter(fieldName, op, value, items, false)
+}
+
+func filter(fieldName string, op string, value interface{}, items interface{}, keep bool) ([]interface{}, error) {
+	values, err := list(reflect.ValueOf(items))
+	if err != nil {
+		return nil, err
+	}
+	rs := make([]interface{}, 0)
+	for _, v := range values {
+		f, err := field(v, fieldName)
+		if err != nil {
+			return nil, err
+		}
+		// items without the field don't match, same as FindBy.
+		ok := false
+		if f.IsValid() {
+			if ok, err = compare(f, op, reflect.ValueOf(value)); err != nil {
+				return nil, err
+			}
+		}
+		if ok == keep {
+			rs = append(rs, v.Interface())
+		}
+	}
+	return rs, nil
+}
+
+// FindBy return the first item whose field equals the given value, or nil if not found.
+// The field can be a struct field or a map key, i.e: find_by "ID" .AuthorID .Authors.
+func FindBy(fieldName string, value interface{}, items interface{}) (interface{}, error) {
+	values, err := list(reflect.ValueOf(items))
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		f, err := field(v, fieldName)
+		if err != nil {
+			return nil, err
+		}
+		if !f.IsValid() {
+			continue
+		}
+		ok, err := compare(f, "eq", reflect.ValueOf(value))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return v.Interface(), nil
+		}
+	}
+	return nil, nil
+}
+
+// IndexBy return a map of the items keyed by their field formatted as string, i.e: index_by "ID" .Authors.
+// The last item wins if there are items with the same key, items without the field are skipped.
+func IndexBy(fieldName string, items interface{}) (map[string]interface{}, error) {
+	values, err := list(reflect.ValueOf(items))
+	if err != nil {
+		return nil, err
+	}
+	rs := make(map[string]interface{}, len(values))
+	for _, v := range values {
+		f, err := field(v, fieldName)
+		if err != nil {
+			return nil, err
+		}
+		if !f.IsValid() {
+			continue
+		}
+		rs[fmt.Sprintf("%v", f.Interface())] = v.Interface()
+	}
+	return rs, nil
+}
+
+// CountBy return the number of occurrences of the values of the field of the items, keyed by the values formatted as string,
+// i.e: count_by "Tags" .Posts for a tag cloud. Values of slice fields are counted one by one, items without the field are skipped.
+func CountBy(fieldName string, items interface{}) (map[string]int, error) {
+	values, err := list(reflect.ValueOf(items))
+	if err != nil {
+		return nil, err
+	}
+	rs := make(map[string]int)
+	for _, v := range values {
+		f, err := field(v, fieldName)
+		if err != nil {
+			return nil, err
+		}
+		if !f.IsValid() {
+			continue
+		}
+		if fv, _ := indirect(f); fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
+			if err := tally(rs, fv); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := tally(rs, reflect.ValueOf([]interface{}{f.Interface()})); err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// Tally return the number of occurrences of the items, keyed by the items formatted as string,
+// i.e: tally .Tags -> map[go:2 web:1].
+func Tally(items interface{}) (map[string]int, error) {
+	rs := make(map[string]int)
+	if err := tally(rs, reflect.ValueOf(items)); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// tally add the occurrences of the items to the counts, nil items are skipped.
+func tally(counts map[string]int, items reflect.Value) error {
+	values, err := list(items)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		if v, isNil := indirect(v); isNil || !v.IsValid() {
+			continue
+		}
+		counts[fmt.Sprintf("%v", v.Interface())]++
+	}
+	return nil
+}
+
+// ByCount return the keys of the counts ordered by their counts descending, then by the keys,
+// so that the order is deterministic, i.e: {{$tags := count_by "Tags" .Posts}}{{range by_count $tags}}.
+func ByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// compare evaluates the comparison of v and the given value using the given operator.
+func compare(v reflect.Value, op string, value reflect.Value) (bool, error) {
+	v, _ = indirect(v)
+	value, _ = indirect(value)
+	switch op {
+	case "eq":
+		return eq(v, value)
+	case "ne":
+		ok, err := eq(v, value)
+		return !ok, err
+	case "lt":
+		return lt(v, value)
+	case "le":
+		ok, err := lt(value, v)
+		return !ok, err
+	case "gt":
+		return lt(value, v)
+	case "ge":
+		ok, err := lt(v, value)
+		return !ok, err
+	case "contains":
+		return has(v, value), nil
+	}
+	return false, fmt.Errorf("unsupported operator %s", op)
+}
+
+// Related return top n items which share the most tags with the current item, excluding the current item.
+// Tags are read from the given field of each item, which can be a struct field or a map key.
+// Items which share no tags are ignored.
+func Related(current interface{}, items interface{}, tagsField string, n int) ([]interface{}, error) {
+	tags, err := field(reflect.ValueOf(current), tagsField)
+	if err != nil {
+		return nil, err
+	}
+	values, err := list(reflect.ValueOf(items))
+	if err != nil {
+		return nil, err
+	}
+	type scored struct {
+		item  interface{}
+		score int
+	}
+	rs := make([]scored, 0)
+	for _, v := range values {
+		item := v.Interface()
+		if reflect.DeepEqual(item, current) {
+			continue
+		}
+		itemTags, err := field(v, tagsField)
+		if err != nil {
+			return nil, err
+		}
+		tt, err := list(itemTags)
+		if err != nil {
+			return nil, err
+		}
+		score := 0
+		for _, tag := range tt {
+			if has(tags, tag) {
+				score++
+			}
+		}
+		if score > 0 {
+			rs = append(rs, scored{item: item, score: score})
+		}
+	}
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].score > rs[j].score
+	})
+	if n >= 0 && len(rs) > n {
+		rs = rs[:n]
+	}
+	related := make([]interface{}, 0, len(rs))
+	for _, r := range rs {
+		related = append(related, r.item)
+	}
+	return related, nil
+}
@@ -0,0 +1,351 @@
+package funcs_test
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	tt "github.com/pthethanh/tiny/funcs"
+)
+
+func TestRelated(t *testing.T) {
+	type post struct {
+		Title string
+		Tags  []string
+	}
+	posts := []post{
+		{Title: "current", Tags: []string{"go", "web", "template"}},
+		{Title: "one", Tags: []string{"go"}},
+		{Title: "none", Tags: []string{"rust"}},
+		{Title: "three", Tags: []string{"template", "go", "web"}},
+		{Title: "two", Tags: []string{"web", "go"}},
+	}
+	testIt(t, []testCase{
+		{
+			name:     "rank by overlap",
+			template: `{{range related (index . 0) . "Tags" 5}}{{.Title}},{{end}}`,
+			data:     posts,
+			output:   "three,two,one,",
+		},
+		{
+			name:     "top n",
+			template: `{{range related (index . 0) . "Tags" 1}}{{.Title}},{{end}}`,
+			data:     posts,
+			output:   "three,",
+		},
+		{
+			name:     "map items",
+			template: `{{range related (index . 0) . "tags" 5}}{{.title}},{{end}}`,
+			data: []map[string]interface{}{
+				{"title": "current", "tags": []interface{}{"go", "web"}},
+				{"title": "one", "tags": []interface{}{"web"}},
+				{"title": "no tags"},
+			},
+			output: "one,",
+		},
+	})
+}
+
+func TestWhere(t *testing.T) {
+	type post struct {
+		Title  string
+		Status string
+		Views  int
+		Tags   []string
+	}
+	posts := []post{
+		{Title: "a", Status: "published", Views: 10, Tags: []string{"go"}},
+		{Title: "b", Status: "draft", Views: 100, Tags: []string{"rust"}},
+		{Title: "c", Status: "published", Views: 50, Tags: []string{"go", "web"}},
+	}
+	testIt(t, []testCase{
+		{
+			name:     "eq string field",
+			template: `{{range where "Status" "eq" "published" .}}{{.Title}},{{end}}`,
+			data:     posts,
+			output:   "a,c,",
+		},
+		{
+			name:     "ne string field",
+			template: `{{range where "Status" "ne" "published" .}}{{.Title}},{{end}}`,
+			data:     posts,
+			output:   "b,",
+		},
+		{
+			name:     "gt numeric field",
+			template: `{{range where "Views" "gt" 20 .}}{{.Title}},{{end}}`,
+			data:     posts,
+			output:   "b,c,",
+		},
+		{
+			name:     "le numeric field with float",
+			template: `{{range where "Views" "le" 50.0 .}}{{.Title}},{{end}}`,
+			data:     posts,
+			output:   "a,c,",
+		},
+		{
+			name:     "contains",
+			template: `{{range where "Tags" "contains" "go" .}}{{.Title}},{{end}}`,
+			data:     posts,
+			output:   "a,c,",
+		},
+		{
+			name:     "reject",
+			template: `{{range reject "Status" "eq" "draft" .}}{{.Title}},{{end}}`,
+			data:     posts,
+			output:   "a,c,",
+		},
+		{
+			name:     "map items",
+			template: `{{range where "n" "lt" 2 .}}{{.n}},{{end}}`,
+			data:     []map[string]int{{"n": 1}, {"n": 2}, {"n": 0}},
+			output:   "1,0,",
+		},
+		{
+			name:     "where heterogeneous map items",
+			template: `{{range where "status" "eq" "published" .}}{{.title}},{{end}}`,
+			data:     []map[string]interface{}{{"title": "a", "status": "published"}, {"title": "b"}, {"title": "c", "status": "published"}},
+			output:   "a,c,",
+		},
+		{
+			name:     "reject heterogeneous map items",
+			template: `{{range reject "views" "gt" 10 .}}{{.title}},{{end}}`,
+			data:     []map[string]interface{}{{"title": "a", "views": 20}, {"title": "b"}, {"title": "c", "views": 5}},
+			output:   "b,c,",
+		},
+	})
+}
+
+func TestAt(t *testing.T) {
+	items := []string{"a", "b", "c"}
+	testIt(t, []testCase{
+		{
+			name:     "in range",
+			template: `{{at . 1}}`,
+			data:     items,
+			output:   "b",
+		},
+		{
+			name:     "out of range default",
+			template: `{{at . 3 "none"}}`,
+			data:     items,
+			output:   "none",
+		},
+		{
+			name:     "out of range nil",
+			template: `{{if at . 3}}found{{else}}nil{{end}}`,
+			data:     items,
+			output:   "nil",
+		},
+		{
+			name:     "negative index",
+			template: `{{at . -1}}`,
+			data:     items,
+			output:   "c",
+		},
+		{
+			name:     "negative out of range",
+			template: `{{at . -4 "none"}}`,
+			data:     items,
+			output:   "none",
+		},
+		{
+			name:     "array",
+			template: `{{at . 0}}`,
+			data:     [2]int{7, 8},
+			output:   "7",
+		},
+		{
+			name:     "string",
+			template: `{{at . 1}}`,
+			data:     "héllo",
+			output:   "é",
+		},
+		{
+			name:     "string negative index",
+			template: `{{at . -2}}`,
+			data:     "héllo",
+			output:   "l",
+		},
+		{
+			name:     "string out of range",
+			template: `{{at . 10 "-"}}`,
+			data:     "héllo",
+			output:   "-",
+		},
+		{
+			name:     "nil",
+			template: `{{at . 0 "none"}}`,
+			data:     nil,
+			output:   "none",
+		},
+	})
+}
+
+func TestSeq(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "ascending",
+			template: `{{range seq 1 5}}{{.}},{{end}}`,
+			output:   "1,2,3,4,5,",
+		},
+		{
+			name:     "descending",
+			template: `{{range seq 3 -1}}{{.}},{{end}}`,
+			output:   "3,2,1,0,-1,",
+		},
+		{
+			name:     "single element",
+			template: `{{range seq 2 2}}{{.}},{{end}}`,
+			output:   "2,",
+		},
+		{
+			name:     "until",
+			template: `{{range until 3}}{{.}},{{end}}`,
+			output:   "0,1,2,",
+		},
+		{
+			name:     "until empty",
+			template: `{{range until 0}}{{.}},{{else}}empty{{end}}`,
+			output:   "empty",
+		},
+		{
+			name:     "until negative",
+			template: `{{len (until -2)}}`,
+			output:   "0",
+		},
+		{
+			name:     "pagination",
+			template: `{{range seq 1 .}}[{{.}}]{{end}}`,
+			data:     3,
+			output:   "[1][2][3]",
+		},
+	})
+	for _, tpl := range []string{`{{seq 1 1000000}}`, `{{seq 1000000 1}}`, `{{until 1000000}}`} {
+		tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(tpl))
+		if err := tmpl.Execute(&bytes.Buffer{}, nil); err == nil {
+			t.Errorf("template: %s, got err=nil, want error for too long sequence", tpl)
+		}
+	}
+}
+
+func TestFindByIndexBy(t *testing.T) {
+	type author struct {
+		ID   int
+		Name string
+	}
+	data := map[string]interface{}{
+		"authors": []author{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}},
+		"posts": []map[string]interface{}{
+			{"title": "hello", "author": 2},
+			{"title": "world", "author": 1},
+			{"title": "anonymous"},
+		},
+	}
+	testIt(t, []testCase{
+		{
+			name:     "found",
+			template: `{{(find_by "ID" 2 .authors).Name}}`,
+			data:     data,
+			output:   "bob",
+		},
+		{
+			name:     "not found",
+			template: `{{with find_by "ID" 3 .authors}}{{.Name}}{{else}}none{{end}}`,
+			data:     data,
+			output:   "none",
+		},
+		{
+			name:     "map items",
+			template: `{{(find_by "author" 1 .posts).title}}`,
+			data:     data,
+			output:   "world",
+		},
+		{
+			name:     "join",
+			template: `{{$authors := .authors}}{{range .posts}}{{with .author}}{{(find_by "ID" . $authors).Name}},{{end}}{{end}}`,
+			data:     data,
+			output:   "bob,alice,",
+		},
+		{
+			name:     "index",
+			template: `{{$m := index_by "Name" .authors}}{{$m.alice.ID}} {{(index $m "bob").ID}} {{len $m}}`,
+			data:     data,
+			output:   "1 2 2",
+		},
+		{
+			name:     "index by int field",
+			template: `{{$m := index_by "ID" .authors}}{{(index $m "2").Name}}`,
+			data:     data,
+			output:   "bob",
+		},
+		{
+			name:     "index skips missing field",
+			template: `{{len (index_by "author" .posts)}}`,
+			data:     data,
+			output:   "2",
+		},
+	})
+}
+
+func TestCountByTally(t *testing.T) {
+	type post struct {
+		Title    string
+		Category string
+		Tags     []string
+	}
+	data := map[string]interface{}{
+		"posts": []post{
+			{Title: "a", Category: "go", Tags: []string{"go", "web"}},
+			{Title: "b", Category: "go", Tags: []string{"go"}},
+			{Title: "c", Category: "devops", Tags: []string{"docker", "go"}},
+		},
+		"maps": []map[string]interface{}{
+			{"lang": "go"},
+			{"lang": "rust"},
+			{"lang": "go"},
+			{"title": "no lang"},
+		},
+		"tags": []string{"web", "go", "web", "css", "go", "web"},
+	}
+	testIt(t, []testCase{
+		{
+			name:     "count by struct field",
+			template: `{{$m := count_by "Category" .posts}}{{$m.go}} {{$m.devops}} {{len $m}}`,
+			data:     data,
+			output:   "2 1 2",
+		},
+		{
+			name:     "count by slice field",
+			template: `{{$m := count_by "Tags" .posts}}{{range by_count $m}}{{.}}:{{index $m .}} {{end}}`,
+			data:     data,
+			output:   "go:3 docker:1 web:1 ",
+		},
+		{
+			name:     "count by map key skips missing",
+			template: `{{$m := count_by "lang" .maps}}{{$m.go}} {{$m.rust}} {{len $m}}`,
+			data:     data,
+			output:   "2 1 2",
+		},
+		{
+			name:     "tally",
+			template: `{{$m := tally .tags}}{{range by_count $m}}{{.}}:{{index $m .}} {{end}}`,
+			data:     data,
+			output:   "web:3 go:2 css:1 ",
+		},
+		{
+			name:     "tally ints",
+			template: `{{$m := tally (seq 1 3)}}{{index $m "2"}} {{len $m}}`,
+			output:   "1 3",
+		},
+		{
+			name:     "tally nil",
+			template: `{{len (tally nil)}}`,
+			output:   "0",
+		},
+	})
+	tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(`{{count_by "Author" .}}`))
+	if err := tmpl.Execute(&bytes.Buffer{}, data["posts"]); err == nil {
+		t.Errorf("got err=nil, want error for unknown field")
+	}
+}
@@ -0,0 +1,12 @@
+package funcs
+
+import "time"
+
+// SetNow override the current time for testing, the returned func restores it.
+func SetNow(f func() time.Time) func() {
+	old := now
+	now = f
+	return func() {
+		now = old
+	}
+}
@@ -0,0 +1,55 @@
+package funcs
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	frontMatterDelim = []byte("---")
+)
+
+// ParseFrontMatter parse the leading `---` delimited YAML block of the given content.
+// It returns the front-matter as a map and the remaining content.
+// If the content has no front-matter, an empty map and the whole content are returned.
+func ParseFrontMatter(b []byte) (map[string]interface{}, []byte, error) {
+	m := map[string]interface{}{}
+	content := bytes.TrimPrefix(b, []byte("\ufeff"))
+	if !bytes.HasPrefix(content, frontMatterDelim) {
+		return m, b, nil
+	}
+	// the opening delimiter must be on its own line.
+	first := bytes.IndexByte(content, '\n')
+	if first < 0 || len(bytes.TrimSpace(content[:first])) != len(frontMatterDelim) {
+		return m, b, nil
+	}
+	rest := content[first+1:]
+	end := -1
+	for i := 0; i < len(rest); {
+		line := rest[i:]
+		n := bytes.IndexByte(line, '\n')
+		if n >= 0 {
+			line = line[:n]
+		}
+		if bytes.Equal(bytes.TrimSpace(line), frontMatterDelim) {
+			end = i
+			break
+		}
+		if n < 0 {
+			break
+		}
+		i += n + 1
+	}
+	if end < 0 {
+		// no closing delimiter, treat as normal content.
+		return m, b, nil
+	}
+	if err := yaml.Unmarshal(rest[:end], &m); err != nil {
+		return nil, nil, err
+	}
+	body := rest[end+len(frontMatterDelim):]
+	body = bytes.TrimLeft(body, " \t")
+	body = bytes.TrimPrefix(bytes.TrimPrefix(body, []byte("\r")), []byte("\n"))
+	return m, body, nil
+}
@@ -0,0 +1,32 @@
+package funcs_test
+
+import (
+	"testing"
+
+	tt "github.com/pthethanh/tiny/funcs"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		title   interface{}
+		body    string
+	}{
+		{name: "with front matter", content: "---\ntitle: Hello\ntags: [go, web]\n---\n# Hello\n", title: "Hello", body: "# Hello\n"},
+		{name: "without front matter", content: "# Hello\n---\nnot front matter\n", body: "# Hello\n---\nnot front matter\n"},
+		{name: "unclosed front matter", content: "---\ntitle: Hello\n", body: "---\ntitle: Hello\n"},
+	}
+	for _, c := range cases {
+		m, body, err := tt.ParseFrontMatter([]byte(c.content))
+		if err != nil {
+			t.Fatalf("%s: got err=%v, want no error", c.name, err)
+		}
+		if m["title"] != c.title {
+			t.Errorf("%s: got title=%v, want title=%v", c.name, m["title"], c.title)
+		}
+		if string(body) != c.body {
+			t.Errorf("%s: got body=%q, want body=%q", c.name, body, c.body)
+		}
+	}
+}
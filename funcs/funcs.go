@@ -40,6 +40,11 @@ func FuncMap() map[string]interface{} {
 	addFuncs(m, GeneralFuncMap())
 	addFuncs(m, StringFuncMap())
 	addFuncs(m, TimeFuncMap())
+	addFuncs(m, CollectionFuncMap())
+	addFuncs(m, HTMLFuncMap())
+	addFuncs(m, MathFuncMap())
+	addFuncs(m, URLFuncMap())
+	addFuncs(m, MarkdownFuncMap())
 	return m
 }
 
@@ -162,6 +167,52 @@ func eq(arg1 reflect.Value, arg2 ...reflect.Value) (bool, error) {
 	return false, nil
 }
 
+// lt evaluates the comparison a < b.
+func lt(arg1, arg2 reflect.Value) (bool, error) {
+	v1 := indirectInterface(arg1)
+	k1, err := basicKind(v1)
+	if err != nil {
+		return false, err
+	}
+	v2 := indirectInterface(arg2)
+	k2, err := basicKind(v2)
+	if err != nil {
+		return false, err
+	}
+	truth := false
+	if k1 != k2 {
+		// Special case: Can compare integer values regardless of type's sign.
+		switch {
+		case k1 == intKind && k2 == uintKind:
+			truth = v1.Int() < 0 || uint64(v1.Int()) < v2.Uint()
+		case k1 == uintKind && k2 == intKind:
+			truth = v2.Int() >= 0 && v1.Uint() < uint64(v2.Int())
+		case k1 == intKind && k2 == floatKind:
+			truth = float64(v1.Int()) < v2.Float()
+		case k1 == floatKind && k2 == intKind:
+			truth = v1.Float() < float64(v2.Int())
+		default:
+			return false, errBadComparison
+		}
+	} else {
+		switch k1 {
+		case boolKind, complexKind:
+			return false, errBadComparisonType
+		case floatKind:
+			truth = v1.Float() < v2.Float()
+		case intKind:
+			truth = v1.Int() < v2.Int()
+		case stringKind:
+			truth = v1.String() < v2.String()
+		case uintKind:
+			truth = v1.Uint() < v2.Uint()
+		default:
+			return false, errBadComparisonType
+		}
+	}
+	return truth, nil
+}
+
 // indirect returns the item at the end of indirection, and a bool to indicate
 // if it's nil. If the returned bool is true, the returned value's kind will be
 // either a pointer or interface.
@@ -211,3 +262,43 @@ func printableValue(v reflect.Value) interface{} {
 	}
 	return v.Interface()
 }
+
+// field returns the value of the named field of a struct or key of a map,
+// following pointers and interfaces.
+func field(item reflect.Value, name string) (reflect.Value, error) {
+	v, isNil := indirect(item)
+	if isNil {
+		return zero, fmt.Errorf("nil value has no field %s", name)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			return zero, fmt.Errorf("%s has no field %s", v.Type(), name)
+		}
+		return f, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return zero, fmt.Errorf("map key of %s is not a string", v.Type())
+		}
+		return v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())), nil
+	}
+	return zero, fmt.Errorf("can't get field %s of type %s", name, v.Type())
+}
+
+// list returns the elements of the given slice or array.
+func list(items reflect.Value) ([]reflect.Value, error) {
+	v, isNil := indirect(items)
+	if isNil || !v.IsValid() {
+		return nil, nil
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		rs := make([]reflect.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			rs = append(rs, v.Index(i))
+		}
+		return rs, nil
+	}
+	return nil, fmt.Errorf("can't iterate over type %s", v.Type())
+}
@@ -1,11 +1,18 @@
 package funcs
 
 import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"html/template"
+	"net/url"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -13,21 +20,32 @@ import (
 // GeneralFuncMap return general func map.
 func GeneralFuncMap() map[string]interface{} {
 	return map[string]interface{}{
-		"is_empty":  IsEmpty,
-		"default":   Default,
-		"ternary":   YesNo,
-		"coalesce":  Coalesce,
-		"env":       os.Getenv,
-		"has":       Has,
-		"has_any":   HasAny,
-		"file_size": FileSizeFormat,
-		"uuid":      UUID,
-		"repeat":    Repeat,
-		"join":      Join,
-		"eq_any":    EqualAny,
-		"deep_eq":   reflect.DeepEqual,
-		"map":       Map,
-		"safe_html": SafeHTML,
+		"is_empty":     IsEmpty,
+		"default":      Default,
+		"ternary":      YesNo,
+		"coalesce":     Coalesce,
+		"env":          os.Getenv,
+		"env_int":      EnvInt,
+		"env_bool":     EnvBool,
+		"env_duration": EnvDuration,
+		"has":          Has,
+		"has_any":      HasAny,
+		"file_size":    FileSizeFormat,
+		"uuid":         UUID,
+		"nonce":        Nonce,
+		"repeat":       Repeat,
+		"join":         Join,
+		"eq_any":       EqualAny,
+		"deep_eq":      reflect.DeepEqual,
+		"map":          Map,
+		"dict":         Map,
+		"list":         List,
+		"append":       Append,
+		"safe_html":    SafeHTML,
+		"page_window":  PageWindow,
+		"page_url":     PageURL,
+		"md5":          MD5,
+		"gravatar":     Gravatar,
 	}
 }
 
@@ -191,6 +209,16 @@ func UUID() string {
 	return uuid.New().String()
 }
 
+// Nonce return a random URL-safe base64 string of 16 bytes, i.e: for the nonce of the Content-Security-Policy.
+// The URL-safe alphabet needs no escaping in HTML attributes and headers.
+func Nonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // FileSizeFormat return human readable string of file size.
 func FileSizeFormat(value interface{}) string {
 	var size float64
@@ -249,3 +277,76 @@ func Map(v ...interface{}) map[string]interface{} {
 	}
 	return m
 }
+
+// List return a slice of the given values, i.e: list "a" "b" -> [a b].
+func List(v ...interface{}) []interface{} {
+	return append([]interface{}{}, v...)
+}
+
+// Append return a new slice of the items of the given slice or array and the values.
+// A nil s is treated as an empty slice.
+func Append(s interface{}, v ...interface{}) ([]interface{}, error) {
+	rs := make([]interface{}, 0)
+	items, isNil := indirect(reflect.ValueOf(s))
+	if !isNil && items.IsValid() {
+		if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+			return nil, fmt.Errorf("append: invalid type %T, must be a slice or an array", s)
+		}
+		for i := 0; i < items.Len(); i++ {
+			rs = append(rs, items.Index(i).Interface())
+		}
+	}
+	return append(rs, v...), nil
+}
+
+// MD5 return hex encoded md5 hash of the given string.
+func MD5(s string) string {
+	h := md5.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// Gravatar return Gravatar URL of the given email with the given size in pixels.
+// An optional default image (i.e: identicon, mp, retro or an URL) can be provided
+// for emails which have no Gravatar.
+func Gravatar(email string, size int, df ...string) string {
+	q := url.Values{}
+	q.Set("s", fmt.Sprintf("%d", size))
+	if len(df) > 0 && df[0] != "" {
+		q.Set("d", df[0])
+	}
+	return fmt.Sprintf("https://www.gravatar.com/avatar/%s?%s", MD5(strings.ToLower(strings.TrimSpace(email))), q.Encode())
+}
+
+// EnvInt return the environment variable as int or df if it's unset or invalid.
+func EnvInt(key string, df int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return df
+	}
+	return v
+}
+
+// EnvBool return the environment variable as bool or df if it's unset or invalid.
+func EnvBool(key string, df bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return df
+	}
+	return v
+}
+
+// EnvDuration return the environment variable as duration or df if it's unset or invalid.
+// df can be either a time.Duration or a duration string, i.e: 1m30s.
+func EnvDuration(key string, df interface{}) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return v
+	}
+	switch d := df.(type) {
+	case time.Duration:
+		return d
+	case string:
+		v, _ := time.ParseDuration(d)
+		return v
+	}
+	return time.Duration(toInt(df))
+}
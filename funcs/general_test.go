@@ -2,11 +2,13 @@ package funcs_test
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"html/template"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	tt "github.com/pthethanh/tiny/funcs"
@@ -134,6 +136,65 @@ func TestEnv(t *testing.T) {
 	}
 }
 
+func TestEnvTyped(t *testing.T) {
+	t.Setenv("TEST_INT", "10")
+	t.Setenv("TEST_INT_INVALID", "ten")
+	t.Setenv("TEST_BOOL", "true")
+	t.Setenv("TEST_BOOL_INVALID", "yes please")
+	t.Setenv("TEST_DURATION", "1m30s")
+	t.Setenv("TEST_DURATION_INVALID", "soon")
+	testIt(t, []testCase{
+		{
+			name:     "int valid",
+			template: `{{env_int "TEST_INT" 5}}`,
+			output:   "10",
+		},
+		{
+			name:     "int invalid",
+			template: `{{env_int "TEST_INT_INVALID" 5}}`,
+			output:   "5",
+		},
+		{
+			name:     "int unset",
+			template: `{{env_int "TEST_INT_UNSET" 5}}`,
+			output:   "5",
+		},
+		{
+			name:     "bool valid",
+			template: `{{env_bool "TEST_BOOL" false}}`,
+			output:   "true",
+		},
+		{
+			name:     "bool invalid",
+			template: `{{env_bool "TEST_BOOL_INVALID" false}}`,
+			output:   "false",
+		},
+		{
+			name:     "bool unset",
+			template: `{{env_bool "TEST_BOOL_UNSET" true}}`,
+			output:   "true",
+		},
+		{
+			name:     "duration valid",
+			template: `{{env_duration "TEST_DURATION" "5s"}}`,
+			output:   "1m30s",
+		},
+		{
+			name:     "duration invalid",
+			template: `{{env_duration "TEST_DURATION_INVALID" "5s"}}`,
+			output:   "5s",
+		},
+		{
+			name:     "duration unset",
+			template: `{{env_duration "TEST_DURATION_UNSET" .D}}`,
+			data: struct {
+				D time.Duration
+			}{D: 2 * time.Second},
+			output: "2s",
+		},
+	})
+}
+
 func TestHas(t *testing.T) {
 	arr := []int{1, 2}
 	x := 5
@@ -285,6 +346,27 @@ func TestUUID(t *testing.T) {
 	})
 }
 
+func TestNonce(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "nonce",
+			template: "{{nonce}} {{nonce}}",
+			verifyFunc: func(got string) error {
+				v := strings.Fields(got)
+				for _, n := range v {
+					if b, err := base64.RawURLEncoding.DecodeString(n); err != nil || len(b) != 16 {
+						return fmt.Errorf("got result=%s, want URL-safe base64 of 16 bytes", n)
+					}
+				}
+				if v[0] == v[1] {
+					return fmt.Errorf("got same nonces=%s, want random nonces", got)
+				}
+				return nil
+			},
+		},
+	})
+}
+
 func TestRepeat(t *testing.T) {
 	x := 5
 	testIt(t, []testCase{
@@ -464,3 +546,98 @@ func testIt(t *testing.T, cases []testCase) {
 		})
 	}
 }
+
+func TestGravatar(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "md5",
+			template: `{{md5 .}}`,
+			data:     "myemailaddress@example.com",
+			output:   "0bc83cb571cd1c50ba6f3e8a78ef1346",
+		},
+		{
+			name:     "gravatar",
+			template: `{{gravatar . 80}}`,
+			data:     " MyEmailAddress@example.com ",
+			output:   "https://www.gravatar.com/avatar/0bc83cb571cd1c50ba6f3e8a78ef1346?s=80",
+		},
+		{
+			name:     "gravatar with default image",
+			template: `<img src="{{gravatar . 40 "identicon"}}">`,
+			data:     "myemailaddress@example.com",
+			output:   `<img src="https://www.gravatar.com/avatar/0bc83cb571cd1c50ba6f3e8a78ef1346?d=identicon&amp;s=40">`,
+		},
+	})
+}
+
+func TestListAppendDict(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "list",
+			template: `{{range list "a" "b" 3}}{{.}},{{end}}`,
+			output:   "a,b,3,",
+		},
+		{
+			name:     "empty list",
+			template: `{{len (list)}}`,
+			output:   "0",
+		},
+		{
+			name:     "append",
+			template: `{{$s := list "a"}}{{$s = append $s "b" "c"}}{{range $s}}{{.}},{{end}}`,
+			output:   "a,b,c,",
+		},
+		{
+			name:     "append to nil",
+			template: `{{range append .Missing "a"}}{{.}},{{end}}`,
+			data:     map[string]interface{}{},
+			output:   "a,",
+		},
+		{
+			name:     "append to typed slice",
+			template: `{{range append . "c"}}{{.}},{{end}}`,
+			data:     []string{"a", "b"},
+			output:   "a,b,c,",
+		},
+		{
+			name:     "dict",
+			template: `{{$d := dict "name" "tiny" "items" (list 1 2)}}{{$d.name}}:{{range $d.items}}{{.}}{{end}}`,
+			output:   "tiny:12",
+		},
+		{
+			name:     "builtin slice",
+			template: `{{slice . 1 3}}`,
+			data:     []int{1, 2, 3, 4},
+			output:   "[2 3]",
+		},
+		{
+			name:     "builtin slice string",
+			template: `{{slice . 1}}`,
+			data:     "hello",
+			output:   "ello",
+		},
+		{
+			name:     "builtin slice string literal",
+			template: `{{slice "Page" 1 3}}`,
+			output:   "ag",
+		},
+		{
+			name:     "builtin slice 3 indices",
+			template: `{{len (slice . 0 1 2)}}`,
+			data:     []int{1, 2, 3},
+			output:   "1",
+		},
+	})
+	for _, tc := range []struct {
+		tpl  string
+		data interface{}
+	}{
+		{tpl: `{{slice . 1 5}}`, data: []int{1, 2}},
+		{tpl: `{{append . 1}}`, data: "not a slice"},
+	} {
+		tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(tc.tpl))
+		if err := tmpl.Execute(&bytes.Buffer{}, tc.data); err == nil {
+			t.Errorf("template: %s, got err=nil, want error", tc.tpl)
+		}
+	}
+}
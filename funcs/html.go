@@ -0,0 +1,385 @@
+package funcs
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	xhtml "golang.org/x/net/html"
+)
+
+var (
+	voidElements = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+		"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+	}
+
+	attrNamePattern = regexp.MustCompile(`^[a-zA-Z_:][-a-zA-Z0-9_:.]*$`)
+
+	// urlAttrs are the attributes whose values are URLs, same as html/template.
+	urlAttrs = map[string]bool{
+		"action": true, "archive": true, "background": true, "cite": true, "classid": true, "codebase": true,
+		"data": true, "formaction": true, "href": true, "icon": true, "longdesc": true, "manifest": true,
+		"poster": true, "profile": true, "src": true, "usemap": true, "xmlns": true,
+	}
+)
+
+const (
+	// unsafeURL replaces the unsafe URLs, same as html/template.
+	unsafeURL = "#ZgotmplZ"
+)
+
+type (
+	// Stars is the star rating of a value, see StarRating.
+	Stars struct {
+		Value float64
+		Max   int
+		Full  int
+		Half  int
+		Empty int
+	}
+)
+
+// HTMLFuncMap return HTML func map.
+func HTMLFuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"attrs":          Attrs,
+		"truncate_html":  TruncateHTML,
+		"excerpt":        Excerpt,
+		"srcset":         Srcset,
+		"sizes":          Sizes,
+		"paragraphs":     Paragraphs,
+		"contrast_color": ContrastColor,
+		"stars":          StarRating,
+		"qr":             QR,
+	}
+}
+
+// Attrs render the given map to an HTML attribute string, i.e: key="value" key2="value2".
+// Attributes are sorted by name, nil and false values are skipped
+// and true values are rendered as boolean attributes.
+// Since the result is trusted by html/template, invalid attribute names and event handlers (on*)
+// which are not template.JS are rejected, and unsafe URLs such as javascript: are replaced by #ZgotmplZ
+// unless they are template.URL.
+func Attrs(attrs interface{}) (template.HTMLAttr, error) {
+	v, isNil := indirect(reflect.ValueOf(attrs))
+	if isNil || !v.IsValid() {
+		return "", nil
+	}
+	if v.Kind() != reflect.Map {
+		return "", fmt.Errorf("attrs: can't render type %s", v.Type())
+	}
+	m := make(map[string]interface{})
+	r := v.MapRange()
+	for r.Next() {
+		m[fmt.Sprintf("%v", printableValue(r.Key()))] = r.Value().Interface()
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		val, isNil := indirect(reflect.ValueOf(m[k]))
+		if isNil || !val.IsValid() {
+			continue
+		}
+		if !attrNamePattern.MatchString(k) {
+			return "", fmt.Errorf("attrs: invalid attribute name: %q", k)
+		}
+		if val.Kind() == reflect.Bool {
+			if val.Bool() {
+				rs = append(rs, k)
+			}
+			continue
+		}
+		str := fmt.Sprintf("%v", printableValue(val))
+		switch name := attrBaseName(k); {
+		case strings.HasPrefix(name, "on"):
+			if _, ok := m[k].(template.JS); !ok {
+				return "", fmt.Errorf("attrs: event handler %s must be template.JS", k)
+			}
+		case isURLAttr(name):
+			if _, ok := m[k].(template.URL); !ok && !isSafeURL(str) {
+				str = unsafeURL
+			}
+		}
+		rs = append(rs, fmt.Sprintf(`%s="%s"`, k, html.EscapeString(str)))
+	}
+	return template.HTMLAttr(strings.Join(rs, " ")), nil
+}
+
+// attrBaseName return the lower case name of the attribute without the data- and namespace prefixes,
+// i.e: data-href -> href, xlink:href -> href.
+func attrBaseName(name string) string {
+	name = strings.TrimPrefix(strings.ToLower(name), "data-")
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// isURLAttr report whether the value of the given attribute is a URL, same as html/template.
+func isURLAttr(name string) bool {
+	return urlAttrs[name] || strings.Contains(name, "src") || strings.Contains(name, "uri") || strings.Contains(name, "url")
+}
+
+// isSafeURL report whether the given URL is relative or has one of the safe schemes: http, https and mailto.
+func isSafeURL(s string) bool {
+	i := strings.IndexByte(s, ':')
+	if i < 0 || strings.Contains(s[:i], "/") {
+		return true
+	}
+	switch strings.ToLower(s[:i]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// TruncateHTML truncate the given HTML to n visible characters, entities are counted as single characters.
+// Tags are kept balanced by closing all the tags which are still open at the cut point.
+func TruncateHTML(n int, s string) (template.HTML, error) {
+	rs := &strings.Builder{}
+	open := make([]string, 0)
+	z := xhtml.NewTokenizer(strings.NewReader(s))
+	for n > 0 {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return "", err
+			}
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case xhtml.TextToken:
+			text := []rune(tok.Data)
+			if len(text) > n {
+				text = text[:n]
+			}
+			n -= len(text)
+			rs.WriteString(html.EscapeString(string(text)))
+		case xhtml.StartTagToken:
+			rs.WriteString(tok.String())
+			if !voidElements[tok.Data] {
+				open = append(open, tok.Data)
+			}
+		case xhtml.EndTagToken:
+			rs.WriteString(tok.String())
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == tok.Data {
+					open = open[:i]
+					break
+				}
+			}
+		default:
+			rs.WriteString(tok.String())
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		rs.WriteString("</" + open[i] + ">")
+	}
+	return template.HTML(rs.String()), nil
+}
+
+// Excerpt return the plain text of the given HTML shortened to at most n characters plus an ellipsis.
+// The text is cut at the last sentence end (., ! or ?) before the limit if it's not too far from the limit,
+// otherwise at the last word boundary. The text is returned as is if it's not longer than n.
+func Excerpt(n int, s string) (string, error) {
+	text, err := stripTags(s)
+	if err != nil {
+		return "", err
+	}
+	rs := []rune(text)
+	if len(rs) <= n {
+		return text, nil
+	}
+	if n <= 0 {
+		return "…", nil
+	}
+	// the sentence end must be followed by a space, so look one character after the limit.
+	for i := n - 1; i >= n/2; i-- {
+		if strings.ContainsRune(".!?", rs[i]) && unicode.IsSpace(rs[i+1]) {
+			return string(rs[:i+1]) + " …", nil
+		}
+	}
+	cut := n
+	for i := n; i > 0; i-- {
+		if unicode.IsSpace(rs[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(rs[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…", nil
+}
+
+// stripTags return the text of the given HTML with whitespaces collapsed,
+// the content of script and style elements are removed.
+func stripTags(s string) (string, error) {
+	rs := &strings.Builder{}
+	skip := 0
+	z := xhtml.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return "", err
+			}
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case xhtml.TextToken:
+			if skip == 0 {
+				rs.WriteString(tok.Data)
+			}
+		case xhtml.StartTagToken, xhtml.EndTagToken, xhtml.SelfClosingTagToken:
+			if tok.Data == "script" || tok.Data == "style" {
+				if tt == xhtml.StartTagToken {
+					skip++
+				} else if tt == xhtml.EndTagToken && skip > 0 {
+					skip--
+				}
+			}
+			// tags separate words, i.e: <p>a</p><p>b</p>.
+			rs.WriteString(" ")
+		}
+	}
+	return strings.Join(strings.Fields(rs.String()), " "), nil
+}
+
+// Srcset build a srcset attribute value referencing the width-suffixed variants of the given image,
+// i.e: srcset "img.jpg" 320 640 -> img-320w.jpg 320w, img-640w.jpg 640w.
+// It doesn't resize the images.
+func Srcset(base string, widths ...int) template.Srcset {
+	ext := path.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	rs := make([]string, 0, len(widths))
+	for _, w := range widths {
+		rs = append(rs, fmt.Sprintf("%s-%dw%s %dw", name, w, ext, w))
+	}
+	return template.Srcset(strings.Join(rs, ", "))
+}
+
+// Sizes build a sizes attribute value from the given source sizes,
+// i.e: sizes "(max-width: 600px) 480px" "800px" -> (max-width: 600px) 480px, 800px.
+func Sizes(sizes ...string) string {
+	return strings.Join(sizes, ", ")
+}
+
+// Paragraphs format the given plain text as HTML paragraphs. Blocks separated by blank lines
+// are wrapped in <p>, single newlines are converted to <br> unless noBreaks is true.
+// The text is escaped.
+func Paragraphs(s string, noBreaks ...bool) template.HTML {
+	s = strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+	rs := &strings.Builder{}
+	block := make([]string, 0)
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		sep := "<br>\n"
+		if len(noBreaks) > 0 && noBreaks[0] {
+			sep = "\n"
+		}
+		rs.WriteString("<p>" + strings.Join(block, sep) + "</p>\n")
+		block = block[:0]
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		block = append(block, html.EscapeString(strings.TrimSpace(line)))
+	}
+	flush()
+	return template.HTML(strings.TrimSuffix(rs.String(), "\n"))
+}
+
+// ContrastColor return the text color which has the best contrast on the given background color,
+// i.e: contrast_color "#1e90ff" -> #000. The background color must be a 3 or 6 digit hex color,
+// #000 is returned for invalid colors.
+func ContrastColor(hex string) template.CSS {
+	r, g, b, ok := parseHexColor(hex)
+	if !ok {
+		return "#000"
+	}
+	// relative luminance as defined by WCAG 2.0.
+	l := 0.2126*linearRGB(r) + 0.7152*linearRGB(g) + 0.0722*linearRGB(b)
+	// contrast ratios with white and black are equal at this luminance.
+	if l > math.Sqrt(1.05*0.05)-0.05 {
+		return "#000"
+	}
+	return "#fff"
+}
+
+// parseHexColor parse the 3 or 6 digit hex color, the leading # is optional.
+func parseHexColor(s string) (r, g, b uint8, ok bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return uint8(v >> 16), uint8(v >> 8), uint8(v), true
+}
+
+// linearRGB convert the sRGB channel value to linear value.
+func linearRGB(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.03928 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// StarRating return the full, half and empty star counts of the rating from 0 to max, i.e: stars 3.5 5
+// gives 3 full, 1 half and 1 empty stars. The value is rounded to the nearest half and clamped to [0, max].
+// The stars can be rendered via Icons, i.e: [[range (stars .Rating 5).Icons]]<i class="star-[[.]]"></i>[[end]].
+func StarRating(value interface{}, max int) (Stars, error) {
+	v, err := toFloat(value)
+	if err != nil {
+		return Stars{}, err
+	}
+	if max <= 0 {
+		return Stars{}, fmt.Errorf("stars: max must be greater than 0, got %d", max)
+	}
+	v = math.Min(math.Max(v, 0), float64(max))
+	halves := int(math.Round(v * 2))
+	s := Stars{Value: v, Max: max, Full: halves / 2, Half: halves % 2}
+	s.Empty = max - s.Full - s.Half
+	return s, nil
+}
+
+// Icons return kinds of the stars in order: full, half or empty.
+func (s Stars) Icons() []string {
+	icons := make([]string, 0, s.Max)
+	for i := 0; i < s.Full; i++ {
+		icons = append(icons, "full")
+	}
+	for i := 0; i < s.Half; i++ {
+		icons = append(icons, "half")
+	}
+	for i := 0; i < s.Empty; i++ {
+		icons = append(icons, "empty")
+	}
+	return icons
+}
@@ -0,0 +1,285 @@
+package funcs_test
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	tt "github.com/pthethanh/tiny/funcs"
+)
+
+func TestAttrs(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "string attrs",
+			template: `<input {{attrs .}}>`,
+			data:     map[string]interface{}{"type": "text", "name": "q", "class": "search"},
+			output:   `<input class="search" name="q" type="text">`,
+		},
+		{
+			name:     "boolean attrs",
+			template: `<input {{attrs .}}>`,
+			data:     map[string]interface{}{"disabled": true, "readonly": false, "value": nil, "id": "x"},
+			output:   `<input disabled id="x">`,
+		},
+		{
+			name:     "escape quotes",
+			template: `<div {{attrs .}}></div>`,
+			data:     map[string]string{"title": `say "hi" & <bye>`},
+			output:   `<div title="say &#34;hi&#34; &amp; &lt;bye&gt;"></div>`,
+		},
+		{
+			name:     "number attrs",
+			template: `<td {{attrs .}}></td>`,
+			data:     map[string]int{"colspan": 2},
+			output:   `<td colspan="2"></td>`,
+		},
+		{
+			name:     "empty",
+			template: `<div {{attrs .}}></div>`,
+			data:     map[string]string{},
+			output:   `<div ></div>`,
+		},
+		{
+			name:     "safe urls",
+			template: `<a {{attrs .}}></a>`,
+			data:     map[string]string{"href": "https://example.com/?q=a", "data-src": "/img.png", "xlink:href": "#top"},
+			output:   `<a data-src="/img.png" href="https://example.com/?q=a" xlink:href="#top"></a>`,
+		},
+		{
+			name:     "unsafe urls",
+			template: `<a {{attrs .}}></a>`,
+			data:     map[string]string{"href": "javascript:alert(1)", "src": " JavaScript:alert(1)", "data-url": "data:text/html,hi"},
+			output:   `<a data-url="#ZgotmplZ" href="#ZgotmplZ" src="#ZgotmplZ"></a>`,
+		},
+		{
+			name:     "trusted url and event handler",
+			template: `<a {{attrs .}}></a>`,
+			data:     map[string]interface{}{"href": template.URL("tel:123"), "onclick": template.JS("go()")},
+			output:   `<a href="tel:123" onclick="go()"></a>`,
+		},
+	})
+	for _, data := range []map[string]interface{}{
+		{"x onmouseover": "alert(1)"},
+		{"x=y": "z"},
+		{"a/b": "z"},
+		{"": "z"},
+		{"onclick": "alert(1)"},
+		{"OnMouseOver": "alert(1)"},
+		{"data-onclick": "alert(1)"},
+	} {
+		err := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(`<div {{attrs .}}></div>`)).Execute(&bytes.Buffer{}, data)
+		if err == nil {
+			t.Errorf("%v: got no error, want error", data)
+		}
+	}
+}
+
+func TestTruncateHTML(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "shorter than limit",
+			template: `{{truncate_html 100 .}}`,
+			data:     `<p>hello <b>world</b></p>`,
+			output:   `<p>hello <b>world</b></p>`,
+		},
+		{
+			name:     "inside a tag",
+			template: `{{truncate_html 3 .}}`,
+			data:     `<p>hello world</p>`,
+			output:   `<p>hel</p>`,
+		},
+		{
+			name:     "across nested tags",
+			template: `{{truncate_html 8 .}}`,
+			data:     `<div><p>hello <b>wor<i>ld</i></b></p><p>next</p></div>`,
+			output:   `<div><p>hello <b>wo</b></p></div>`,
+		},
+		{
+			name:     "void elements",
+			template: `{{truncate_html 4 .}}`,
+			data:     `<p>ab<br>cd<img src="x.png">ef</p>`,
+			output:   `<p>ab<br>cd</p>`,
+		},
+		{
+			name:     "entities as single characters",
+			template: `{{truncate_html 3 .}}`,
+			data:     `<p>a&amp;b&lt;c</p>`,
+			output:   `<p>a&amp;b</p>`,
+		},
+	})
+}
+
+func TestSrcset(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "srcset",
+			template: `<img srcset="{{srcset "/images/img.jpg" 320 640 1024}}">`,
+			output:   `<img srcset="/images/img-320w.jpg 320w, /images/img-640w.jpg 640w, /images/img-1024w.jpg 1024w">`,
+		},
+		{
+			name:     "srcset without extension",
+			template: `{{srcset "img" 320}}`,
+			output:   `img-320w 320w`,
+		},
+		{
+			name:     "sizes",
+			template: `<img sizes="{{sizes "(max-width: 600px) 480px" "800px"}}">`,
+			output:   `<img sizes="(max-width: 600px) 480px, 800px">`,
+		},
+	})
+}
+
+func TestParagraphs(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "multiple paragraphs",
+			template: `{{paragraphs .}}`,
+			data:     "first paragraph\n\n\nsecond paragraph\r\n\r\nthird",
+			output:   "<p>first paragraph</p>\n<p>second paragraph</p>\n<p>third</p>",
+		},
+		{
+			name:     "single newline breaks",
+			template: `{{paragraphs .}}`,
+			data:     "line 1\nline 2\n\nline 3",
+			output:   "<p>line 1<br>\nline 2</p>\n<p>line 3</p>",
+		},
+		{
+			name:     "without breaks",
+			template: `{{paragraphs . true}}`,
+			data:     "line 1\nline 2",
+			output:   "<p>line 1\nline 2</p>",
+		},
+		{
+			name:     "escape",
+			template: `{{paragraphs .}}`,
+			data:     "<script>alert(1)</script>",
+			output:   "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>",
+		},
+		{
+			name:     "empty",
+			template: `{{paragraphs .}}`,
+			data:     " \n\n ",
+			output:   "",
+		},
+	})
+}
+
+func TestContrastColor(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "white background",
+			template: `{{contrast_color "#ffffff"}}`,
+			output:   "#000",
+		},
+		{
+			name:     "black background",
+			template: `{{contrast_color "#000000"}}`,
+			output:   "#fff",
+		},
+		{
+			name:     "light 3 digits",
+			template: `{{contrast_color "#ff0"}}`,
+			output:   "#000",
+		},
+		{
+			name:     "dark 3 digits",
+			template: `{{contrast_color "#00f"}}`,
+			output:   "#fff",
+		},
+		{
+			name:     "dark without hash",
+			template: `{{contrast_color "333333"}}`,
+			output:   "#fff",
+		},
+		{
+			name:     "mid gray",
+			template: `{{contrast_color "#777"}}`,
+			output:   "#000",
+		},
+		{
+			name:     "invalid length",
+			template: `{{contrast_color "#abcd"}}`,
+			output:   "#000",
+		},
+		{
+			name:     "invalid digits",
+			template: `{{contrast_color "#zzzzzz"}}`,
+			output:   "#000",
+		},
+		{
+			name:     "empty",
+			template: `{{contrast_color ""}}`,
+			output:   "#000",
+		},
+	})
+}
+
+func TestExcerpt(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "sentence boundary",
+			template: `{{excerpt 40 .}}`,
+			data:     "<p>Tiny is a small site engine. It renders <b>pages</b> from YAML config.</p>",
+			output:   "Tiny is a small site engine. …",
+		},
+		{
+			name:     "question and exclamation",
+			template: `{{excerpt 30 .}}`,
+			data:     "Is it tiny? Yes! It really is tiny and fast.",
+			output:   "Is it tiny? Yes! …",
+		},
+		{
+			name:     "word boundary without punctuation",
+			template: `{{excerpt 20 .}}`,
+			data:     "<p>one two three four five six seven</p>",
+			output:   "one two three four…",
+		},
+		{
+			name:     "sentence too far from the limit",
+			template: `{{excerpt 30 .}}`,
+			data:     "Hi. This is a long sentence without an early end",
+			output:   "Hi. This is a long sentence…",
+		},
+		{
+			name:     "short text",
+			template: `{{excerpt 100 .}}`,
+			data:     "<p>Short   text.</p>\n<p>Second.</p>",
+			output:   "Short text. Second.",
+		},
+		{
+			name:     "script removed",
+			template: `{{excerpt 100 .}}`,
+			data:     "<script>alert(1)</script><style>p{}</style>Hello &amp; welcome",
+			output:   "Hello &amp; welcome",
+		},
+		{
+			name:     "long word",
+			template: `{{excerpt 5 .}}`,
+			data:     "abcdefghij",
+			output:   "abcde…",
+		},
+	})
+}
+
+func TestStarRating(t *testing.T) {
+	const tpl = `{{with stars . 5}}{{.Full}}/{{.Half}}/{{.Empty}}|{{range .Icons}}{{.}} {{end}}{{end}}`
+	testIt(t, []testCase{
+		{name: "whole", template: tpl, data: 4, output: "4/0/1|full full full full empty "},
+		{name: "half", template: tpl, data: 2.5, output: "2/1/2|full full half empty empty "},
+		{name: "rounded to half", template: tpl, data: 3.74, output: "3/1/1|full full full half empty "},
+		{name: "rounded up", template: tpl, data: 4.8, output: "5/0/0|full full full full full "},
+		{name: "clamped max", template: tpl, data: 7.2, output: "5/0/0|full full full full full "},
+		{name: "clamped min", template: tpl, data: -1, output: "0/0/5|empty empty empty empty empty "},
+		{name: "other max", template: `{{with stars . 10}}{{.Full}}/{{.Half}}/{{.Empty}}{{end}}`, data: 6.5, output: "6/1/3"},
+	})
+}
+
+func TestStarRatingError(t *testing.T) {
+	for _, tpl := range []string{`{{stars . 5}}`, `{{stars 3 0}}`} {
+		tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(tpl))
+		if err := tmpl.Execute(&bytes.Buffer{}, "bad"); err == nil {
+			t.Errorf("template: %s, got err=nil, want error", tpl)
+		}
+	}
+}
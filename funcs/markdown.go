@@ -0,0 +1,53 @@
+package funcs
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+var (
+	// markdownRenderer does not render raw HTML and dangerous links
+	// such as javascript: URLs, hence the output is safe from user content.
+	markdownRenderer = goldmark.New(
+		goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.Linkify),
+	)
+	// unsafeMarkdownRenderer renders raw HTML as is, it must be used for trusted content only.
+	unsafeMarkdownRenderer = goldmark.New(
+		goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.Linkify),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+)
+
+// MarkdownFuncMap return Markdown func map.
+func MarkdownFuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"markdown":      Markdown,
+		"markdown_safe": MarkdownSafe,
+	}
+}
+
+// Markdown convert the given Markdown string to HTML.
+// Fenced code blocks, tables and autolinks are supported.
+// Raw HTML in the Markdown is omitted and dangerous links are removed.
+func Markdown(s string) (template.HTML, error) {
+	return renderMarkdown(markdownRenderer, s)
+}
+
+// MarkdownSafe convert the given trusted Markdown string to HTML,
+// raw HTML in the Markdown is rendered as is.
+func MarkdownSafe(s string) (template.HTML, error) {
+	return renderMarkdown(unsafeMarkdownRenderer, s)
+}
+
+func renderMarkdown(md goldmark.Markdown, s string) (template.HTML, error) {
+	buf := &bytes.Buffer{}
+	if err := md.Convert([]byte(s), buf); err != nil {
+		return "", err
+	}
+	return template.HTML(strings.TrimSuffix(buf.String(), "\n")), nil
+}
@@ -0,0 +1,76 @@
+package funcs_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "emphasis",
+			template: `{{markdown .}}`,
+			data:     "some **bold** and *italic* `code`",
+			output:   "<p>some <strong>bold</strong> and <em>italic</em> <code>code</code></p>",
+		},
+		{
+			name:     "heading and list",
+			template: `{{markdown .}}`,
+			data:     "# Title\n\n- one\n- two",
+			output:   "<h1>Title</h1>\n<ul>\n<li>one</li>\n<li>two</li>\n</ul>",
+		},
+		{
+			name:     "link",
+			template: `{{markdown .}}`,
+			data:     "[home](https://example.com/?a=1&b=2)",
+			output:   `<p><a href="https://example.com/?a=1&amp;b=2">home</a></p>`,
+		},
+		{
+			name:     "empty",
+			template: `{{markdown .}}`,
+			data:     "",
+			output:   "",
+		},
+		{
+			name:     "raw html is stripped",
+			template: `{{markdown .}}`,
+			data:     "hello <script>alert(1)</script>\n\n<div onclick=\"alert(1)\">x</div>\n\n[x](javascript:alert(1))",
+			verifyFunc: func(s string) error {
+				for _, v := range []string{"<script", "onclick", "javascript:"} {
+					if strings.Contains(s, v) {
+						return fmt.Errorf("got output=%s, want %s stripped", s, v)
+					}
+				}
+				if !strings.Contains(s, "hello") {
+					return fmt.Errorf("got output=%s, want text kept", s)
+				}
+				return nil
+			},
+		},
+		{
+			name:     "fenced code block",
+			template: `{{markdown .}}`,
+			data:     "```go\nfmt.Println(\"<hello>\")\n```",
+			output:   "<pre><code class=\"language-go\">fmt.Println(&quot;&lt;hello&gt;&quot;)\n</code></pre>",
+		},
+		{
+			name:     "table",
+			template: `{{markdown .}}`,
+			data:     "| a | b |\n| - | - |\n| 1 | 2 |",
+			output:   "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>",
+		},
+		{
+			name:     "autolink",
+			template: `{{markdown .}}`,
+			data:     "visit https://example.com now",
+			output:   `<p>visit <a href="https://example.com">https://example.com</a> now</p>`,
+		},
+		{
+			name:     "safe keeps raw html",
+			template: `{{markdown_safe .}}`,
+			data:     "# Title\n\n<div class=\"note\">hello</div>",
+			output:   "<h1>Title</h1>\n<div class=\"note\">hello</div>",
+		},
+	})
+}
@@ -0,0 +1,366 @@
+package funcs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MathFuncMap return math func map.
+func MathFuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"pct":      Percent,
+		"pct_str":  PercentString,
+		"div":      Div,
+		"div_safe": DivSafe,
+		"add":      Add,
+		"sub":      Sub,
+		"mul":      Mul,
+		"mod":      Mod,
+		"min":      Min,
+		"max":      Max,
+		"ceil":     Ceil,
+		"floor":    Floor,
+		"round":    Round,
+		"number":   Number,
+		"currency": Currency,
+	}
+}
+
+type (
+	numberLocale struct {
+		group   string
+		decimal string
+		// symbolAfter reports whether the currency symbol is placed after the amount.
+		symbolAfter bool
+	}
+
+	currencyInfo struct {
+		symbol   string
+		decimals int
+	}
+)
+
+var (
+	numberLocales = map[string]numberLocale{
+		"en": {group: ",", decimal: "."},
+		"de": {group: ".", decimal: ",", symbolAfter: true},
+		"es": {group: ".", decimal: ",", symbolAfter: true},
+		"it": {group: ".", decimal: ",", symbolAfter: true},
+		"fr": {group: "\u202f", decimal: ",", symbolAfter: true},
+		"vi": {group: ".", decimal: ",", symbolAfter: true},
+	}
+
+	currencies = map[string]currencyInfo{
+		"USD": {symbol: "$", decimals: 2},
+		"EUR": {symbol: "€", decimals: 2},
+		"GBP": {symbol: "£", decimals: 2},
+		"JPY": {symbol: "¥", decimals: 0},
+		"CNY": {symbol: "¥", decimals: 2},
+		"INR": {symbol: "₹", decimals: 2},
+		"KRW": {symbol: "₩", decimals: 0},
+		"VND": {symbol: "₫", decimals: 0},
+	}
+)
+
+var (
+	errDivisionByZero = errors.New("division by zero")
+)
+
+// Div return a / b. The result is an int if both a and b are integers, otherwise a float64.
+// Division by zero returns an error.
+func Div(a, b interface{}) (interface{}, error) {
+	if isInt(a) && isInt(b) {
+		x, y := toInt(a), toInt(b)
+		if y == 0 {
+			return nil, errDivisionByZero
+		}
+		return int(x / y), nil
+	}
+	x, err := toFloat(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return nil, err
+	}
+	if y == 0 {
+		return nil, errDivisionByZero
+	}
+	return x / y, nil
+}
+
+// Add return a + b. The result is an int if both a and b are integers, otherwise a float64.
+func Add(a, b interface{}) (interface{}, error) {
+	return arith(a, b, func(x, y int64) int64 { return x + y }, func(x, y float64) float64 { return x + y })
+}
+
+// Sub return a - b. The result is an int if both a and b are integers, otherwise a float64.
+func Sub(a, b interface{}) (interface{}, error) {
+	return arith(a, b, func(x, y int64) int64 { return x - y }, func(x, y float64) float64 { return x - y })
+}
+
+// Mul return a * b. The result is an int if both a and b are integers, otherwise a float64.
+func Mul(a, b interface{}) (interface{}, error) {
+	return arith(a, b, func(x, y int64) int64 { return x * y }, func(x, y float64) float64 { return x * y })
+}
+
+// Mod return the remainder of a / b. The result is an int if both a and b are integers, otherwise a float64.
+// Division by zero returns an error.
+func Mod(a, b interface{}) (interface{}, error) {
+	y, err := toFloat(b)
+	if err != nil {
+		return nil, err
+	}
+	if y == 0 {
+		return nil, errDivisionByZero
+	}
+	return arith(a, b, func(x, y int64) int64 { return x % y }, math.Mod)
+}
+
+// Min return the smallest of the given numbers. The result is an int if all of them are integers, otherwise a float64.
+func Min(a interface{}, others ...interface{}) (interface{}, error) {
+	return pick(a, others, func(x, y float64) bool { return y < x })
+}
+
+// Max return the largest of the given numbers. The result is an int if all of them are integers, otherwise a float64.
+func Max(a interface{}, others ...interface{}) (interface{}, error) {
+	return pick(a, others, func(x, y float64) bool { return y > x })
+}
+
+// Ceil return the least integer value greater than or equal to v.
+func Ceil(v interface{}) (float64, error) {
+	x, err := toFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	return math.Ceil(x), nil
+}
+
+// Floor return the greatest integer value less than or equal to v.
+func Floor(v interface{}) (float64, error) {
+	x, err := toFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	return math.Floor(x), nil
+}
+
+// Round round v to the given precision (default 0), half away from zero.
+func Round(v interface{}, precision ...int) (float64, error) {
+	x, err := toFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	return roundTo(x, getPrecision(precision)), nil
+}
+
+// arith apply the int operator if both a and b are integers, otherwise the float operator.
+func arith(a, b interface{}, intOp func(x, y int64) int64, floatOp func(x, y float64) float64) (interface{}, error) {
+	if isInt(a) && isInt(b) {
+		// int is returned so that the result can be used as argument of the int funcs, i.e: seq, page_window.
+		return int(intOp(toInt(a), toInt(b))), nil
+	}
+	x, err := toFloat(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return nil, err
+	}
+	return floatOp(x, y), nil
+}
+
+// pick return the value which is preferred over the others by the better func.
+func pick(a interface{}, others []interface{}, better func(x, y float64) bool) (interface{}, error) {
+	allInt := isInt(a)
+	rs, err := toFloat(a)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range others {
+		f, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		allInt = allInt && isInt(v)
+		if better(rs, f) {
+			rs = f
+		}
+	}
+	if allInt {
+		return int(rs), nil
+	}
+	return rs, nil
+}
+
+// DivSafe is similar to Div but return the default value if b is zero.
+func DivSafe(a, b, df interface{}) (interface{}, error) {
+	y, err := toFloat(b)
+	if err != nil {
+		return nil, err
+	}
+	if y == 0 {
+		return df, nil
+	}
+	return Div(a, b)
+}
+
+// Percent return percentage of part in whole, rounded to the given precision (default 0).
+// Zero whole returns 0.
+func Percent(part interface{}, whole interface{}, precision ...int) (float64, error) {
+	p, err := toFloat(part)
+	if err != nil {
+		return 0, err
+	}
+	w, err := toFloat(whole)
+	if err != nil {
+		return 0, err
+	}
+	if w == 0 {
+		return 0, nil
+	}
+	return roundTo(p/w*100, getPrecision(precision)), nil
+}
+
+// PercentString is similar to Percent but format the result with % appended, i.e: 42%.
+func PercentString(part interface{}, whole interface{}, precision ...int) (string, error) {
+	v, err := Percent(part, whole, precision...)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(v, 'f', getPrecision(precision), 64) + "%", nil
+}
+
+func getPrecision(precision []int) int {
+	if len(precision) == 0 || precision[0] < 0 {
+		return 0
+	}
+	return precision[0]
+}
+
+// Number format the given number with the digits grouped by thousands, i.e: 1234567.5 -> 1,234,567.5.
+// An optional locale (default to en) can be provided, i.e: number 1234.5 "de" -> 1.234,5.
+func Number(v interface{}, locale ...string) (string, error) {
+	if isInt(v) {
+		return formatNumber(strconv.FormatInt(toInt(v), 10), getNumberLocale(locale)), nil
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return "", err
+	}
+	return formatNumber(strconv.FormatFloat(f, 'f', -1, 64), getNumberLocale(locale)), nil
+}
+
+// Currency format the amount with the symbol and decimals of the given ISO 4217 currency code,
+// i.e: currency "USD" 12.5 -> $12.50. An optional locale (default to en) can be provided,
+// i.e: currency "EUR" 1234.5 "de" -> 1.234,50 €. Unknown codes are used as the symbol.
+func Currency(code string, amount interface{}, locale ...string) (string, error) {
+	f, err := toFloat(amount)
+	if err != nil {
+		return "", err
+	}
+	code = strings.ToUpper(code)
+	info, ok := currencies[code]
+	if !ok {
+		info = currencyInfo{symbol: code + " ", decimals: 2}
+	}
+	loc := getNumberLocale(locale)
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	n := formatNumber(strconv.FormatFloat(f, 'f', info.decimals, 64), loc)
+	if loc.symbolAfter {
+		return sign + n + " " + strings.TrimSpace(info.symbol), nil
+	}
+	return sign + info.symbol + n, nil
+}
+
+func getNumberLocale(locale []string) numberLocale {
+	if len(locale) > 0 {
+		// i.e: de-DE, de_DE -> de
+		lang := strings.ToLower(strings.SplitN(strings.ReplaceAll(locale[0], "_", "-"), "-", 2)[0])
+		if loc, ok := numberLocales[lang]; ok {
+			return loc
+		}
+	}
+	return numberLocales["en"]
+}
+
+// formatNumber group the integer part of the formatted number and replace its decimal point.
+func formatNumber(s string, loc numberLocale) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+	rs := &strings.Builder{}
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			rs.WriteString(loc.group)
+		}
+		rs.WriteRune(c)
+	}
+	if frac != "" {
+		rs.WriteString(loc.decimal + frac)
+	}
+	return sign + rs.String()
+}
+
+// roundTo round v to the given number of decimal places.
+func roundTo(v float64, precision int) float64 {
+	p := math.Pow(10, float64(precision))
+	return math.Round(v*p) / p
+}
+
+// isInt report whether the given value is an int or uint.
+func isInt(v interface{}) bool {
+	val, isNil := indirect(reflect.ValueOf(v))
+	if isNil {
+		return false
+	}
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// toInt convert the given int or uint value to int64, other values are converted to 0.
+func toInt(v interface{}) int64 {
+	val, _ := indirect(reflect.ValueOf(v))
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(val.Uint())
+	}
+	return 0
+}
+
+// toFloat convert the given int, uint or float value to float64.
+func toFloat(v interface{}) (float64, error) {
+	val, isNil := indirect(reflect.ValueOf(v))
+	if isNil {
+		return 0, fmt.Errorf("invalid number: nil")
+	}
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(val.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), nil
+	}
+	return 0, fmt.Errorf("invalid number: %v", v)
+}
@@ -0,0 +1,253 @@
+package funcs_test
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	tt "github.com/pthethanh/tiny/funcs"
+)
+
+func TestPercent(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "normal ratio",
+			template: `{{pct 42 100}}`,
+			output:   "42",
+		},
+		{
+			name:     "mixed int and float",
+			template: `{{pct 1.5 6}}`,
+			output:   "25",
+		},
+		{
+			name:     "zero whole",
+			template: `{{pct 5 0}}`,
+			output:   "0",
+		},
+		{
+			name:     "round",
+			template: `{{pct 2 3}}`,
+			output:   "67",
+		},
+		{
+			name:     "round with precision",
+			template: `{{pct 1 3 2}}`,
+			output:   "33.33",
+		},
+		{
+			name:     "string",
+			template: `{{pct_str 1 4}}`,
+			output:   "25%",
+		},
+		{
+			name:     "string with precision",
+			template: `{{pct_str 1 8 1}}`,
+			output:   "12.5%",
+		},
+		{
+			name:     "string zero whole",
+			template: `{{pct_str 0 0 1}}`,
+			output:   "0.0%",
+		},
+	})
+}
+
+func TestDiv(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "int division",
+			template: `{{div 10 3}}`,
+			output:   "3",
+		},
+		{
+			name:     "float division",
+			template: `{{div 10 4.0}}`,
+			output:   "2.5",
+		},
+		{
+			name:     "safe division",
+			template: `{{div_safe 9 3 "n/a"}}`,
+			output:   "3",
+		},
+		{
+			name:     "safe division by zero",
+			template: `{{div_safe 9 0 "n/a"}}`,
+			output:   "n/a",
+		},
+		{
+			name:     "safe division by float zero",
+			template: `{{div_safe 9 0.0 0}}`,
+			output:   "0",
+		},
+	})
+}
+
+func TestDivByZero(t *testing.T) {
+	for _, tpl := range []string{`{{div 1 0}}`, `{{div 1.5 0.0}}`, `{{mod 1 0}}`, `{{mod 1.5 0.0}}`} {
+		tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(tpl))
+		if err := tmpl.Execute(&bytes.Buffer{}, nil); err == nil {
+			t.Errorf("template: %s, got err=nil, want division by zero error", tpl)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "add int",
+			template: `{{add 1 2}}`,
+			output:   "3",
+		},
+		{
+			name:     "add int and float",
+			template: `{{add 1 0.5}}`,
+			output:   "1.5",
+		},
+		{
+			name:     "sub",
+			template: `{{sub 10 12}}`,
+			output:   "-2",
+		},
+		{
+			name:     "sub float",
+			template: `{{sub 2.5 1}}`,
+			output:   "1.5",
+		},
+		{
+			name:     "mul",
+			template: `{{mul 3 4}}`,
+			output:   "12",
+		},
+		{
+			name:     "mul int and float",
+			template: `{{mul 3 0.5}}`,
+			output:   "1.5",
+		},
+		{
+			name:     "mod",
+			template: `{{mod 10 3}}`,
+			output:   "1",
+		},
+		{
+			name:     "mod float",
+			template: `{{mod 5.5 2}}`,
+			output:   "1.5",
+		},
+		{
+			name:     "min",
+			template: `{{min 3 1 2}}`,
+			output:   "1",
+		},
+		{
+			name:     "min mixed",
+			template: `{{min 3 1.5}}`,
+			output:   "1.5",
+		},
+		{
+			name:     "max",
+			template: `{{max 3 7 2}}`,
+			output:   "7",
+		},
+		{
+			name:     "max single",
+			template: `{{max 3}}`,
+			output:   "3",
+		},
+		{
+			name:     "ceil",
+			template: `{{div 7 2.0 | ceil}}`,
+			output:   "4",
+		},
+		{
+			name:     "floor",
+			template: `{{floor 3.7}}`,
+			output:   "3",
+		},
+		{
+			name:     "floor negative",
+			template: `{{floor -3.2}}`,
+			output:   "-4",
+		},
+		{
+			name:     "round",
+			template: `{{round 2.5}}`,
+			output:   "3",
+		},
+		{
+			name:     "round with precision",
+			template: `{{round 3.14159 2}}`,
+			output:   "3.14",
+		},
+		{
+			name:     "pagination",
+			template: `{{$total := 23}}{{$per := 10}}{{div (add $total (sub $per 1)) $per}} pages, from {{add (mul 2 $per) 1}}`,
+			output:   "3 pages, from 21",
+		},
+		{
+			name:     "int result as int argument",
+			template: `{{range seq 1 (div 10 3)}}{{.}}{{end}} {{until (add 1 2)}} {{page_window 2 (div 100 10) 2}} {{truncate (sub 5 2) "abcdef"}} {{at (list 1 2 3) (sub 2 1)}} {{range seq (min 2 3) (max 3 4)}}{{.}}{{end}} {{seq 1 (mod 7 4)}} {{seq 1 (mul 2 2)}}`,
+			output:   "123 [0 1 2] [1 2 3 4 -1 10] abc 2 234 [1 2 3] [1 2 3 4]",
+		},
+	})
+}
+
+func TestNumberAndCurrency(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "number",
+			template: `{{number 1234567}}`,
+			output:   "1,234,567",
+		},
+		{
+			name:     "small number",
+			template: `{{number 123}}`,
+			output:   "123",
+		},
+		{
+			name:     "negative float",
+			template: `{{number -1234.5}}`,
+			output:   "-1,234.5",
+		},
+		{
+			name:     "number with locale",
+			template: `{{number 1234567.25 "de-DE"}}`,
+			output:   "1.234.567,25",
+		},
+		{
+			name:     "number with unknown locale",
+			template: `{{number 1234 "xx"}}`,
+			output:   "1,234",
+		},
+		{
+			name:     "usd",
+			template: `{{currency "USD" 12.5}}`,
+			output:   "$12.50",
+		},
+		{
+			name:     "usd grouped",
+			template: `{{currency "usd" 1234567}}`,
+			output:   "$1,234,567.00",
+		},
+		{
+			name:     "negative",
+			template: `{{currency "GBP" -3.456}}`,
+			output:   "-£3.46",
+		},
+		{
+			name:     "no decimals",
+			template: `{{currency "JPY" 1500.4}}`,
+			output:   "¥1,500",
+		},
+		{
+			name:     "eur with locale",
+			template: `{{currency "EUR" 1234.5 "de"}}`,
+			output:   "1.234,50 €",
+		},
+		{
+			name:     "unknown code",
+			template: `{{currency "CHF" 9.9}}`,
+			output:   "CHF 9.90",
+		},
+	})
+}
@@ -0,0 +1,92 @@
+package funcs
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// PageGap is the sentinel value returned by PageWindow for a gap "…" between page numbers.
+	PageGap = -1
+)
+
+// PageWindow return the list of page numbers to display for a pagination bar,
+// including the first page, the last page and the pages within window of the current page.
+// Skipped pages are represented by PageGap, i.e: 1 -1 4 5 6 7 8 -1 20.
+func PageWindow(current, total, window int) []int {
+	if total <= 0 {
+		return []int{}
+	}
+	if current < 1 {
+		current = 1
+	}
+	if current > total {
+		current = total
+	}
+	if window < 0 {
+		window = 0
+	}
+	start, end := current-window, current+window
+	if start < 1 {
+		start = 1
+	}
+	if end > total {
+		end = total
+	}
+	pages := make([]int, 0)
+	prev := 0
+	add := func(p int) {
+		switch {
+		case p <= prev:
+			return
+		case p-prev == 2:
+			// no point to hide a single page behind a gap.
+			pages = append(pages, prev+1)
+		case p-prev > 2:
+			pages = append(pages, PageGap)
+		}
+		pages = append(pages, p)
+		prev = p
+	}
+	add(1)
+	for p := start; p <= end; p++ {
+		add(p)
+	}
+	add(total)
+	return pages
+}
+
+// PageURL return the given URL with its page query param set to the given page,
+// the other query params are preserved, i.e: /posts?tag=go&page=2.
+// The URL can be a string, an url.URL or a http.Request.
+func PageURL(v interface{}, page int) (string, error) {
+	var u url.URL
+	switch val := v.(type) {
+	case string:
+		pu, err := url.Parse(val)
+		if err != nil {
+			return "", err
+		}
+		u = *pu
+	case *url.URL:
+		if val != nil {
+			u = *val
+		}
+	case url.URL:
+		u = val
+	case *http.Request:
+		if val != nil && val.URL != nil {
+			u = *val.URL
+		}
+	default:
+		return "", fmt.Errorf("page_url: unsupported type %T", v)
+	}
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+	// only path and query are kept, so that the URL is relative to the current host.
+	res := url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}
+	return res.String(), nil
+}
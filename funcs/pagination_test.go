@@ -0,0 +1,76 @@
+package funcs_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	tt "github.com/pthethanh/tiny/funcs"
+)
+
+func TestPageWindow(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "start",
+			template: `{{page_window 1 20 2}}`,
+			output:   "[1 2 3 -1 20]",
+		},
+		{
+			name:     "middle",
+			template: `{{page_window 6 20 2}}`,
+			output:   "[1 -1 4 5 6 7 8 -1 20]",
+		},
+		{
+			name:     "end",
+			template: `{{page_window 20 20 2}}`,
+			output:   "[1 -1 18 19 20]",
+		},
+		{
+			name:     "single hidden page is shown",
+			template: `{{page_window 4 20 1}}`,
+			output:   "[1 2 3 4 5 -1 20]",
+		},
+		{
+			name:     "small total without gaps",
+			template: `{{page_window 2 5 2}}`,
+			output:   "[1 2 3 4 5]",
+		},
+		{
+			name:     "single page",
+			template: `{{page_window 1 1 2}}`,
+			output:   "[1]",
+		},
+		{
+			name:     "no page",
+			template: `{{page_window 1 0 2}}`,
+			output:   "[]",
+		},
+	})
+}
+
+func TestPageURL(t *testing.T) {
+	u, _ := url.Parse("https://example.com/posts?tag=go&sort=new&page=1")
+	testIt(t, []testCase{
+		{
+			name:     "filters preserved and page updated",
+			template: `<a href="{{page_url . 2}}">`,
+			data:     u,
+			output:   `<a href="/posts?page=2&amp;sort=new&amp;tag=go">`,
+		},
+		{
+			name:     "page added",
+			template: `<a href="{{page_url . 3}}">`,
+			data:     "/posts?tag=go",
+			output:   `<a href="/posts?page=3&amp;tag=go">`,
+		},
+		{
+			name:     "request",
+			template: `<a href="{{page_url . 2}}">`,
+			data:     httptest.NewRequest("GET", "/search?q=tiny+site", nil),
+			output:   `<a href="/search?page=2&amp;q=tiny&#43;site">`,
+		},
+	})
+	if _, err := tt.PageURL(1, 2); err == nil {
+		t.Errorf("got no error, want error for unsupported type")
+	}
+}
@@ -0,0 +1,62 @@
+package funcs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"sync"
+)
+
+type (
+	// QRGenerator generates the QR code image of the content, i.e: a PNG or SVG image,
+	// the size is the width and height of the image in pixels.
+	QRGenerator interface {
+		Generate(content string, size int) (image []byte, mimeType string, err error)
+	}
+
+	// QRGeneratorFunc is an adapter to allow the use of ordinary functions as QRGenerator.
+	QRGeneratorFunc func(content string, size int) ([]byte, string, error)
+)
+
+var (
+	qrMu        sync.RWMutex
+	qrGenerator QRGenerator
+)
+
+// Generate calls f(content, size).
+func (f QRGeneratorFunc) Generate(content string, size int) ([]byte, string, error) {
+	return f(content, size)
+}
+
+// SetQRGenerator register the generator of the `qr` func, nil unregisters it.
+// There is no default generator to avoid a hard dependency on a QR code library,
+// i.e: wrap github.com/skip2/go-qrcode:
+//
+//	funcs.SetQRGenerator(funcs.QRGeneratorFunc(func(content string, size int) ([]byte, string, error) {
+//		b, err := qrcode.Encode(content, qrcode.Medium, size)
+//		return b, "image/png", err
+//	}))
+func SetQRGenerator(g QRGenerator) {
+	qrMu.Lock()
+	defer qrMu.Unlock()
+	qrGenerator = g
+}
+
+// QR return the data URI of the QR code image of the content, i.e: <img src="[[qr .URL 256]]">.
+// An error is returned if no generator is registered, see SetQRGenerator.
+func QR(content string, size int) (template.URL, error) {
+	qrMu.RLock()
+	g := qrGenerator
+	qrMu.RUnlock()
+	if g == nil {
+		return "", fmt.Errorf("qr: no generator registered")
+	}
+	if size <= 0 {
+		return "", fmt.Errorf("qr: size must be greater than 0, got %d", size)
+	}
+	b, mimeType, err := g.Generate(content, size)
+	if err != nil {
+		return "", fmt.Errorf("qr: %w", err)
+	}
+	return template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(b)), nil
+}
@@ -0,0 +1,59 @@
+package funcs_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"testing"
+
+	tt "github.com/pthethanh/tiny/funcs"
+)
+
+func TestQR(t *testing.T) {
+	defer tt.SetQRGenerator(nil)
+	tt.SetQRGenerator(tt.QRGeneratorFunc(func(content string, size int) ([]byte, string, error) {
+		return []byte(fmt.Sprintf("<svg>%s|%d</svg>", content, size)), "image/svg+xml", nil
+	}))
+	const prefix = "data:image/svg+xml;base64,"
+	testIt(t, []testCase{
+		{
+			name:     "data uri",
+			template: `<img src="{{qr . 128}}">`,
+			data:     "BEGIN:VCARD\nFN:Jack\nEND:VCARD",
+			verifyFunc: func(got string) error {
+				// the attribute value is HTML escaped, i.e: + -> &#43;.
+				src := html.UnescapeString(strings.TrimSuffix(strings.TrimPrefix(got, `<img src="`), `">`))
+				if !strings.HasPrefix(src, prefix) {
+					return fmt.Errorf("got src=%s, want prefix=%s", src, prefix)
+				}
+				b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(src, prefix))
+				if err != nil {
+					return err
+				}
+				if want := "<svg>BEGIN:VCARD\nFN:Jack\nEND:VCARD|128</svg>"; string(b) != want {
+					return fmt.Errorf("got image=%s, want image=%s", b, want)
+				}
+				return nil
+			},
+		},
+	})
+}
+
+func TestQRError(t *testing.T) {
+	defer tt.SetQRGenerator(nil)
+	tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(`{{qr . 128}}`))
+	tt.SetQRGenerator(nil)
+	if err := tmpl.Execute(&bytes.Buffer{}, "https://example.com"); err == nil {
+		t.Errorf("got err=nil, want error without generator")
+	}
+	tt.SetQRGenerator(tt.QRGeneratorFunc(func(content string, size int) ([]byte, string, error) {
+		return nil, "", errors.New("content too long")
+	}))
+	if err := tmpl.Execute(&bytes.Buffer{}, "https://example.com"); err == nil || !strings.Contains(err.Error(), "content too long") {
+		t.Errorf("got err=%v, want generator error", err)
+	}
+}
@@ -2,29 +2,369 @@ package funcs
 
 import (
 	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // StringFuncMap return string func map.
 func StringFuncMap() map[string]interface{} {
 	return map[string]interface{}{
-		"upper":       strings.ToUpper,
-		"lower":       strings.ToLower,
-		"string":      func(v interface{}) string { return fmt.Sprintf("%v", v) },
-		"trim":        func(c, s string) string { return strings.Trim(s, c) },
-		"trim_left":   func(c, s string) string { return strings.TrimLeft(s, c) },
-		"trim_right":  func(c, s string) string { return strings.TrimRight(s, c) },
-		"trim_prefix": func(c, s string) string { return strings.TrimPrefix(s, c) },
-		"trim_suffix": func(c, s string) string { return strings.TrimSuffix(s, c) },
-		"title":       strings.Title,
-		"fields":      strings.Fields,
-		"wc":          func(s string) int { return len(strings.Fields(s)) },
-		"has_prefix":  func(c, s string) bool { return strings.HasPrefix(s, c) },
-		"has_suffix":  func(c, s string) bool { return strings.HasSuffix(s, c) },
-		"replace":     func(old, new string, n int, s string) string { return strings.Replace(s, old, new, n) },
-		"replace_all": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
-		"count":       func(sub, s string) int { return strings.Count(s, sub) },
-		"split":       func(sep, s string) []string { return strings.Split(s, sep) },
-		"split_n":     func(sep string, n int, s string) []string { return strings.SplitN(s, sep, n) },
+		"upper":           strings.ToUpper,
+		"lower":           strings.ToLower,
+		"string":          func(v interface{}) string { return fmt.Sprintf("%v", v) },
+		"trim":            func(c, s string) string { return strings.Trim(s, c) },
+		"trim_left":       func(c, s string) string { return strings.TrimLeft(s, c) },
+		"trim_right":      func(c, s string) string { return strings.TrimRight(s, c) },
+		"trim_prefix":     func(c, s string) string { return strings.TrimPrefix(s, c) },
+		"trim_suffix":     func(c, s string) string { return strings.TrimSuffix(s, c) },
+		"title":           strings.Title,
+		"fields":          strings.Fields,
+		"wc":              func(s string) int { return len(strings.Fields(s)) },
+		"has_prefix":      func(c, s string) bool { return strings.HasPrefix(s, c) },
+		"has_suffix":      func(c, s string) bool { return strings.HasSuffix(s, c) },
+		"replace":         func(old, new string, n int, s string) string { return strings.Replace(s, old, new, n) },
+		"replace_all":     func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"count":           func(sub, s string) int { return strings.Count(s, sub) },
+		"split":           func(sep, s string) []string { return strings.Split(s, sep) },
+		"split_n":         func(sep string, n int, s string) []string { return strings.SplitN(s, sep, n) },
+		"humanize":        Humanize,
+		"humanize_title":  HumanizeTitle,
+		"mask":            Mask,
+		"mask_email":      MaskEmail,
+		"is_email":        IsEmail,
+		"normalize_email": NormalizeEmail,
+		"pluralize":       Pluralize,
+		"closest":         Closest,
+		"list_join":       ListJoin,
+		"truncate":        Truncate,
+		"ellipsis":        Ellipsis,
+		"slugify":         Slugify,
+		"slugify_ascii":   SlugifyASCII,
+		"interpolate":     Interpolate,
+	}
+}
+
+var (
+	// emailPattern is a conservative pattern of the email addresses, the domain must have at least 2 labels.
+	emailPattern = regexp.MustCompile(`^[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+(\.[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+)*@[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+$`)
+
+	// slugReplacer transliterates the letters which are not decomposed into ASCII letters and marks.
+	slugReplacer = strings.NewReplacer(
+		"đ", "d", "Đ", "d", "ß", "ss", "æ", "ae", "Æ", "ae", "œ", "oe", "Œ", "oe",
+		"ø", "o", "Ø", "o", "ł", "l", "Ł", "l", "þ", "th", "Þ", "th",
+	)
+)
+
+// Slugify convert the given string to a URL-safe slug, i.e: "Hello, World!" -> hello-world.
+// Accented characters are transliterated, i.e: "Xin chào" -> xin-chao. Other letters
+// such as CJK are kept, use SlugifyASCII for removing them.
+func Slugify(s string) string {
+	return slugify(s, false)
+}
+
+// SlugifyASCII is similar to Slugify but the non-ASCII characters are removed.
+func SlugifyASCII(s string) string {
+	return slugify(s, true)
+}
+
+func slugify(s string, asciiOnly bool) string {
+	s = norm.NFD.String(slugReplacer.Replace(s))
+	rs := &strings.Builder{}
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// drop the combining marks of the decomposed accented characters.
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)),
+			!asciiOnly && r > unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if hyphen && rs.Len() > 0 {
+				rs.WriteByte('-')
+			}
+			hyphen = false
+			rs.WriteRune(r)
+		case r > unicode.MaxASCII && asciiOnly:
+			// non-ASCII characters are removed without separating the words.
+			continue
+		default:
+			hyphen = true
+		}
+	}
+	return norm.NFC.String(rs.String())
+}
+
+// Truncate cut the given string to at most n characters, multi-byte characters are not split.
+func Truncate(n int, s string) string {
+	if n <= 0 {
+		return ""
+	}
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+	return string(rs[:n])
+}
+
+// Ellipsis is similar to Truncate but append "…" if the string is cut.
+func Ellipsis(n int, s string) string {
+	if rs := Truncate(n, s); rs != s {
+		return rs + "…"
+	}
+	return s
+}
+
+// Pluralize return the singular form if n is 1 or -1, otherwise return the plural form,
+// which is default to the singular form plus "s" if not provided, i.e: pluralize 2 "comment" -> comments.
+func Pluralize(n interface{}, singular string, plural ...string) (string, error) {
+	v, err := toFloat(n)
+	if err != nil {
+		return "", err
+	}
+	if math.Abs(v) == 1 {
+		return singular, nil
+	}
+	if len(plural) > 0 {
+		return plural[0], nil
+	}
+	return singular + "s", nil
+}
+
+// ListJoin join the items into a human-readable series with the Oxford comma,
+// i.e: "Go, Rust, and Python". The conjunction is default to "and".
+func ListJoin(items interface{}, conjunction ...string) (string, error) {
+	values, err := list(reflect.ValueOf(items))
+	if err != nil {
+		return "", err
+	}
+	conj := "and"
+	if len(conjunction) > 0 {
+		conj = conjunction[0]
+	}
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		strs = append(strs, fmt.Sprintf("%v", printableValue(v)))
+	}
+	switch len(strs) {
+	case 0:
+		return "", nil
+	case 1:
+		return strs[0], nil
+	case 2:
+		return strs[0] + " " + conj + " " + strs[1], nil
+	}
+	return strings.Join(strs[:len(strs)-1], ", ") + ", " + conj + " " + strs[len(strs)-1], nil
+}
+
+// Mask keep the first and the last keep characters of the string and mask the middle with *.
+// Strings which are too short to keep any characters are masked entirely.
+func Mask(s string, keep int) string {
+	rs := []rune(s)
+	if keep < 0 {
+		keep = 0
+	}
+	if len(rs) <= keep*2 {
+		return strings.Repeat("*", len(rs))
+	}
+	return string(rs[:keep]) + strings.Repeat("*", len(rs)-keep*2) + string(rs[len(rs)-keep:])
+}
+
+// MaskEmail keep the first character of the local part and the domain of the email and mask the rest,
+// i.e: john@example.com -> j***@example.com.
+func MaskEmail(s string) string {
+	i := strings.LastIndex(s, "@")
+	if i < 0 {
+		return Mask(s, 1)
+	}
+	local := []rune(s[:i])
+	if len(local) <= 1 {
+		return strings.Repeat("*", len(local)) + s[i:]
+	}
+	return string(local[:1]) + strings.Repeat("*", len(local)-1) + s[i:]
+}
+
+// IsEmail report whether the given string is a valid email address, i.e: john.doe@example.com.
+// The pattern is conservative: quoted local parts, IP domains and non-ASCII characters are not accepted.
+func IsEmail(s string) bool {
+	// 254 is the max length of a forward path in SMTP.
+	return len(s) <= 254 && emailPattern.MatchString(s)
+}
+
+// NormalizeEmail trim the spaces and lower case the given email address.
+func NormalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// Humanize split the given snake_case, kebab-case or camelCase string into lower case words
+// and upper case the first letter of the first word, i.e: in_progress -> In progress.
+func Humanize(s string) string {
+	return humanize(s, false)
+}
+
+// HumanizeTitle is similar to Humanize but upper case the first letter of all words,
+// i.e: in_progress -> In Progress.
+func HumanizeTitle(s string) string {
+	return humanize(s, true)
+}
+
+func humanize(s string, title bool) string {
+	ws := words(s)
+	for i, w := range ws {
+		w = strings.ToLower(w)
+		if i == 0 || title {
+			rs := []rune(w)
+			rs[0] = unicode.ToUpper(rs[0])
+			w = string(rs)
+		}
+		ws[i] = w
+	}
+	return strings.Join(ws, " ")
+}
+
+// words split the given string into words by spaces, underscores, hyphens
+// and case changes, i.e: HTTPServer_name -> [HTTP Server name].
+func words(s string) []string {
+	rs := make([]string, 0)
+	runes := []rune(s)
+	start := -1
+	for i, r := range runes {
+		if r == '_' || r == '-' || unicode.IsSpace(r) {
+			if start >= 0 {
+				rs = append(rs, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				rs = append(rs, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		rs = append(rs, string(runes[start:]))
+	}
+	return rs
+}
+
+// Closest return at most n candidates which are closest to s by Levenshtein distance,
+// the closest one first. Candidates equal to s are excluded.
+func Closest(s string, candidates []string, n int) []string {
+	type match struct {
+		value string
+		dist  int
+	}
+	matches := make([]match, 0, len(candidates))
+	for _, c := range candidates {
+		if c == s {
+			continue
+		}
+		matches = append(matches, match{value: c, dist: Levenshtein(s, c)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].dist == matches[j].dist {
+			return matches[i].value < matches[j].value
+		}
+		return matches[i].dist < matches[j].dist
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n > len(matches) {
+		n = len(matches)
+	}
+	rs := make([]string, 0, n)
+	for _, m := range matches[:n] {
+		rs = append(rs, m.value)
+	}
+	return rs
+}
+
+// Levenshtein return the minimum number of single-character edits (insertions, deletions or substitutions)
+// required to change a into b.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// Interpolate replace the {key} placeholders of the string with the values of the map,
+// i.e: interpolate "Hello, {name}!" (dict "name" "Jack") -> Hello, Jack!.
+// Unknown placeholders are kept as is, unless the optional missing value is provided to replace them.
+// Use {{ and }} for literal braces.
+func Interpolate(s string, values interface{}, missing ...string) (string, error) {
+	v, isNil := indirect(reflect.ValueOf(values))
+	if !isNil && v.IsValid() && (v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String) {
+		return "", fmt.Errorf("interpolate: values must be a map of string keys, got %s", v.Type())
+	}
+	lookup := func(key string) (string, bool) {
+		if isNil || !v.IsValid() {
+			return "", false
+		}
+		val := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		if !val.IsValid() {
+			return "", false
+		}
+		return fmt.Sprintf("%v", printableValue(val)), true
+	}
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c == '{' || c == '}') && i+1 < len(s) && s[i+1] == c {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		end := strings.IndexByte(s[i+1:], '}')
+		if c != '{' || end < 0 {
+			b.WriteByte(c)
+			continue
+		}
+		key := s[i+1 : i+1+end]
+		if val, ok := lookup(key); ok {
+			b.WriteString(val)
+		} else if len(missing) > 0 {
+			b.WriteString(missing[0])
+		} else {
+			b.WriteString("{" + key + "}")
+		}
+		i += end + 1
 	}
+	return b.String(), nil
 }
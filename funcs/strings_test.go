@@ -1,7 +1,12 @@
 package funcs_test
 
 import (
+	"bytes"
+	"fmt"
+	"html/template"
 	"testing"
+
+	tt "github.com/pthethanh/tiny/funcs"
 )
 
 func TestStringTrim(t *testing.T) {
@@ -139,3 +144,428 @@ func TestStringCommon(t *testing.T) {
 		},
 	})
 }
+
+func TestHumanize(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "snake",
+			template: `{{.|humanize}}`,
+			data:     "in_progress",
+			output:   "In progress",
+		},
+		{
+			name:     "kebab",
+			template: `{{.|humanize}}`,
+			data:     "not-started-yet",
+			output:   "Not started yet",
+		},
+		{
+			name:     "camel",
+			template: `{{.|humanize}}`,
+			data:     "PascalCase",
+			output:   "Pascal case",
+		},
+		{
+			name:     "camel with acronym",
+			template: `{{.|humanize}}`,
+			data:     "parseHTTPServer2Config",
+			output:   "Parse http server2 config",
+		},
+		{
+			name:     "already humanized",
+			template: `{{.|humanize}}`,
+			data:     "In progress",
+			output:   "In progress",
+		},
+		{
+			name:     "title",
+			template: `{{.|humanize_title}}`,
+			data:     "in_progress",
+			output:   "In Progress",
+		},
+		{
+			name:     "title camel",
+			template: `{{.|humanize_title}}`,
+			data:     "__waitingForReview__",
+			output:   "Waiting For Review",
+		},
+		{
+			name:     "empty",
+			template: `{{.|humanize}}`,
+			data:     "",
+			output:   "",
+		},
+	})
+}
+
+func TestMask(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "normal string",
+			template: `{{mask . 4}}`,
+			data:     "sk_live_1234567890",
+			output:   "sk_l**********7890",
+		},
+		{
+			name:     "short string fully masked",
+			template: `{{mask . 4}}`,
+			data:     "secret",
+			output:   "******",
+		},
+		{
+			name:     "multi-byte characters",
+			template: `{{mask . 1}}`,
+			data:     "héllo wörld",
+			output:   "h*********d",
+		},
+		{
+			name:     "email",
+			template: `{{mask_email .}}`,
+			data:     "john.doe@example.com",
+			output:   "j*******@example.com",
+		},
+		{
+			name:     "email single character local part",
+			template: `{{mask_email .}}`,
+			data:     "j@example.com",
+			output:   "*@example.com",
+		},
+		{
+			name:     "not an email",
+			template: `{{mask_email .}}`,
+			data:     "johndoe",
+			output:   "j*****e",
+		},
+	})
+}
+
+func TestPluralize(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "zero",
+			template: `{{pluralize 0 "comment"}}`,
+			output:   "comments",
+		},
+		{
+			name:     "one",
+			template: `{{pluralize 1 "comment"}}`,
+			output:   "comment",
+		},
+		{
+			name:     "two",
+			template: `{{pluralize 2 "comment"}}`,
+			output:   "comments",
+		},
+		{
+			name:     "irregular plural",
+			template: `{{pluralize 3 "person" "people"}}`,
+			output:   "people",
+		},
+		{
+			name:     "irregular singular",
+			template: `{{pluralize 1 "person" "people"}}`,
+			output:   "person",
+		},
+		{
+			name:     "negative one",
+			template: `{{pluralize -1 "degree"}}`,
+			output:   "degree",
+		},
+		{
+			name:     "negative",
+			template: `{{pluralize -5 "degree"}}`,
+			output:   "degrees",
+		},
+		{
+			name:     "float",
+			template: `{{pluralize 1.5 "hour"}}`,
+			output:   "hours",
+		},
+	})
+}
+
+func TestClosest(t *testing.T) {
+	paths := []string{"/about", "/blog", "/contact", "/blog/archive", "/pricing"}
+	testIt(t, []testCase{
+		{
+			name:     "close paths rank first",
+			template: `{{closest "/blgo" . 2}}`,
+			data:     paths,
+			output:   "[/blog /about]",
+		},
+		{
+			name:     "typo",
+			template: `{{index (closest "/contcat" . 1) 0}}`,
+			data:     paths,
+			output:   "/contact",
+		},
+		{
+			name:     "exact match excluded",
+			template: `{{closest "/about" . 1}}`,
+			data:     paths,
+			output:   "[/blog]",
+		},
+		{
+			name:     "n larger than candidates",
+			template: `{{len (closest "/x" . 10)}}`,
+			data:     paths,
+			output:   "5",
+		},
+	})
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+	for _, c := range cases {
+		if got := tt.Levenshtein(c.a, c.b); got != c.want {
+			t.Errorf("levenshtein(%s, %s), got=%d, want=%d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestListJoin(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "empty",
+			template: `{{list_join .}}`,
+			data:     []string{},
+			output:   "",
+		},
+		{
+			name:     "nil",
+			template: `{{list_join .}}`,
+			data:     nil,
+			output:   "",
+		},
+		{
+			name:     "single",
+			template: `{{list_join .}}`,
+			data:     []string{"Go"},
+			output:   "Go",
+		},
+		{
+			name:     "two",
+			template: `{{list_join .}}`,
+			data:     []string{"Go", "Rust"},
+			output:   "Go and Rust",
+		},
+		{
+			name:     "many",
+			template: `{{list_join .}}`,
+			data:     []string{"Go", "Rust", "Python"},
+			output:   "Go, Rust, and Python",
+		},
+		{
+			name:     "conjunction",
+			template: `{{list_join . "or"}}`,
+			data:     []string{"Go", "Rust", "Python"},
+			output:   "Go, Rust, or Python",
+		},
+		{
+			name:     "typed slice",
+			template: `{{list_join .}}`,
+			data:     []int{1, 2, 3, 4},
+			output:   "1, 2, 3, and 4",
+		},
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "ascii",
+			template: `{{truncate 5 "hello world"}}`,
+			output:   "hello",
+		},
+		{
+			name:     "shorter than n",
+			template: `{{truncate 20 "hello"}}`,
+			output:   "hello",
+		},
+		{
+			name:     "exact length",
+			template: `{{truncate 5 "hello"}}`,
+			output:   "hello",
+		},
+		{
+			name:     "emoji",
+			template: `{{truncate 3 "👋🌍🚀✨"}}`,
+			output:   "👋🌍🚀",
+		},
+		{
+			name:     "multi-byte",
+			template: `{{truncate 4 "Xin chào"}}`,
+			output:   "Xin ",
+		},
+		{
+			name:     "zero",
+			template: `{{truncate 0 "hello"}}`,
+			output:   "",
+		},
+		{
+			name:     "ellipsis",
+			template: `{{ellipsis 5 "hello world"}}`,
+			output:   "hello…",
+		},
+		{
+			name:     "ellipsis exact length",
+			template: `{{ellipsis 5 "hello"}}`,
+			output:   "hello",
+		},
+		{
+			name:     "ellipsis shorter than n",
+			template: `{{ellipsis 10 "hello"}}`,
+			output:   "hello",
+		},
+		{
+			name:     "ellipsis emoji",
+			template: `{{ellipsis 2 "🚀🚀🚀"}}`,
+			output:   "🚀🚀…",
+		},
+	})
+}
+
+func TestSlugify(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "punctuation",
+			template: `{{slugify "Hello, World!"}}`,
+			output:   "hello-world",
+		},
+		{
+			name:     "accents",
+			template: `{{slugify "Crème Brûlée à la française"}}`,
+			output:   "creme-brulee-a-la-francaise",
+		},
+		{
+			name:     "vietnamese",
+			template: `{{slugify "Đường đến Hà Nội"}}`,
+			output:   "duong-den-ha-noi",
+		},
+		{
+			name:     "special letters",
+			template: `{{slugify "Straße Øresund"}}`,
+			output:   "strasse-oresund",
+		},
+		{
+			name:     "multiple spaces",
+			template: `{{slugify "go    is   fun"}}`,
+			output:   "go-is-fun",
+		},
+		{
+			name:     "leading and trailing symbols",
+			template: `{{slugify "--- #Go 1.16: what's new? ---"}}`,
+			output:   "go-1-16-what-s-new",
+		},
+		{
+			name:     "non latin kept",
+			template: `{{slugify "Hello 世界"}}`,
+			output:   "hello-世界",
+		},
+		{
+			name:     "ascii only",
+			template: `{{slugify_ascii "Hello 世界 and café"}}`,
+			output:   "hello-and-cafe",
+		},
+		{
+			name:     "empty",
+			template: `{{slugify "!!!"}}`,
+			output:   "",
+		},
+	})
+}
+
+func TestEmail(t *testing.T) {
+	cases := []struct {
+		email string
+		valid bool
+	}{
+		{email: "john@example.com", valid: true},
+		{email: "john.doe+news@mail.example.co.uk", valid: true},
+		{email: "o'brien@example-mail.com", valid: true},
+		{email: "john@localhost", valid: false},
+		{email: "john@", valid: false},
+		{email: "@example.com", valid: false},
+		{email: "john.@example.com", valid: false},
+		{email: "jo..hn@example.com", valid: false},
+		{email: "john@-example.com", valid: false},
+		{email: "john@example..com", valid: false},
+		{email: "john doe@example.com", valid: false},
+		{email: "john@example.com ", valid: false},
+		{email: "", valid: false},
+	}
+	tcs := make([]testCase, 0, len(cases)+1)
+	for _, c := range cases {
+		tcs = append(tcs, testCase{
+			name:     "is_email " + c.email,
+			template: `{{is_email .}}`,
+			data:     c.email,
+			output:   fmt.Sprintf("%v", c.valid),
+		})
+	}
+	tcs = append(tcs, testCase{
+		name:     "normalize_email",
+		template: `{{normalize_email .}}|{{is_email (normalize_email .)}}`,
+		data:     "  John.Doe@Example.COM\n",
+		output:   "john.doe@example.com|true",
+	})
+	testIt(t, tcs)
+}
+
+func TestInterpolate(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "present keys",
+			template: `{{interpolate "Hello, {name}! You have {count} new {item}." .}}`,
+			data:     map[string]interface{}{"name": "Jack", "count": 3, "item": "messages"},
+			output:   "Hello, Jack! You have 3 new messages.",
+		},
+		{
+			name:     "string map",
+			template: `{{interpolate "{greeting}, {name}" .}}`,
+			data:     map[string]string{"greeting": "Xin chào", "name": "Thanh"},
+			output:   "Xin chào, Thanh",
+		},
+		{
+			name:     "missing keys kept",
+			template: `{{interpolate "Hello, {name}! {unknown}" .}}`,
+			data:     map[string]interface{}{"name": "Jack"},
+			output:   "Hello, Jack! {unknown}",
+		},
+		{
+			name:     "missing keys replaced",
+			template: `{{interpolate "Hello, {name}!{unknown}" . ""}}`,
+			data:     map[string]interface{}{"name": "Jack"},
+			output:   "Hello, Jack!",
+		},
+		{
+			name:     "nil map",
+			template: `{{interpolate "Hello, {name}!" .}}`,
+			output:   "Hello, {name}!",
+		},
+		{
+			name:     "escaped braces",
+			template: `{{interpolate "{{name}} is {name}, {{ and }} are braces" .}}`,
+			data:     map[string]interface{}{"name": "Jack"},
+			output:   "{name} is Jack, { and } are braces",
+		},
+		{
+			name:     "unclosed brace",
+			template: `{{interpolate "Hello, {name" .}}`,
+			data:     map[string]interface{}{"name": "Jack"},
+			output:   "Hello, {name",
+		},
+	})
+}
+
+func TestInterpolateError(t *testing.T) {
+	tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(`{{interpolate "{a}" .}}`))
+	if err := tmpl.Execute(&bytes.Buffer{}, []string{"a"}); err == nil {
+		t.Errorf("got err=nil, want error for non-map values")
+	}
+}
@@ -5,18 +5,193 @@ import (
 	"time"
 )
 
+const (
+	// DefaultDateLayout is the default layout of the `today` func.
+	DefaultDateLayout = "2006-01-02"
+)
+
+var (
+	// now return the current time, it can be overridden in tests.
+	now = time.Now
+
+	// timeLayouts are the standard layouts which can be referred by name in ParseTime.
+	timeLayouts = map[string]string{
+		"RFC3339":     time.RFC3339,
+		"RFC3339Nano": time.RFC3339Nano,
+		"RFC1123":     time.RFC1123,
+		"RFC1123Z":    time.RFC1123Z,
+		"RFC822":      time.RFC822,
+		"RFC822Z":     time.RFC822Z,
+		"date":        DefaultDateLayout,
+	}
+)
+
 func TimeFuncMap() map[string]interface{} {
 	return map[string]interface{}{
-		"date":     FormatTime,
-		"duration": FormatDuration,
+		"date":       FormatTime,
+		"duration":   FormatDuration,
+		"since":      Since,
+		"until_time": Until,
+		"time_diff":  TimeDiff,
+		"year":       Year,
+		"now_unix":   NowUnix,
+		"today":      Today,
+		"time_ago":   TimeAgo,
+		"parse_time": ParseTime,
+		"unix":       Unix,
+		"now":        Now,
+		"add_date":   AddDate,
+		"add_time":   AddTime,
+		"sub_time":   SubTime,
+	}
+}
+
+const (
+	// timeAgoMax is the max distance of the relative time, further times are formatted as dates.
+	timeAgoMax = 30 * 24 * time.Hour
+)
+
+// TimeAgo return the relative time of the given time to now, i.e: 3 hours ago, in 2 days.
+// Times which are more than 30 days away are formatted as dates using DefaultDateLayout.
+// It accepts the same inputs as FormatTime.
+func TimeAgo(date interface{}) string {
+	t := toTime(date)
+	d := now().Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d >= timeAgoMax {
+		return t.Format(DefaultDateLayout)
+	}
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	default:
+		n, unit = int(d/(24*time.Hour)), "day"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	if future {
+		return fmt.Sprintf("in %d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
+
+// ParseTime parse the given value using the layout, the inverse of FormatTime, i.e:
+// parse_time "2006-01-02T15:04:05Z07:00" .PublishedAt.
+// The layout can also be the name of a standard layout: RFC3339, RFC3339Nano, RFC1123, RFC1123Z, RFC822, RFC822Z,
+// or date for DefaultDateLayout. Values without time zone are parsed as UTC.
+func ParseTime(layout string, value string) (time.Time, error) {
+	if l, ok := timeLayouts[layout]; ok {
+		layout = l
+	}
+	return time.Parse(layout, value)
+}
+
+// Unix return the given time as seconds since UNIX epoch.
+// It accepts the same inputs as FormatTime.
+func Unix(date interface{}) int64 {
+	return toTime(date).Unix()
+}
+
+// Now return the current time, i.e: now | date "2006" "UTC".
+func Now() time.Time {
+	return now()
+}
+
+// AddDate return the given time with the years, months and days added, see time.Time.AddDate.
+// It accepts the same inputs as FormatTime, i.e: now | add_date 0 1 0 for the same day next month.
+func AddDate(years, months, days int, date interface{}) time.Time {
+	return toTime(date).AddDate(years, months, days)
+}
+
+// AddTime return the given time with the duration added, i.e: now | add_time "2h".
+// The duration can be a time.Duration, its nanoseconds or a string such as 1h30m.
+// It accepts the same inputs as FormatTime for the time.
+func AddTime(d interface{}, date interface{}) (time.Time, error) {
+	dur, err := toDuration(d)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return toTime(date).Add(dur), nil
+}
+
+// SubTime return the given time with the duration subtracted, i.e: now | sub_time "24h".
+// It accepts the same inputs as AddTime.
+func SubTime(d interface{}, date interface{}) (time.Time, error) {
+	dur, err := toDuration(d)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return toTime(date).Add(-dur), nil
+}
+
+// toDuration convert the given time.Duration, nanoseconds or duration string to duration.
+func toDuration(d interface{}) (time.Duration, error) {
+	switch d := d.(type) {
+	case time.Duration:
+		return d, nil
+	case int64:
+		return time.Duration(d), nil
+	case int:
+		return time.Duration(d), nil
+	case string:
+		return time.ParseDuration(d)
+	}
+	return 0, fmt.Errorf("invalid duration: %v", d)
+}
+
+// Year return the current year in the given time zone, default to the local time zone.
+// An error is returned if the time zone is invalid.
+func Year(zone ...string) (int, error) {
+	t, err := nowIn(zone...)
+	if err != nil {
+		return 0, err
+	}
+	return t.Year(), nil
+}
+
+// NowUnix return the current time as seconds since UNIX epoch.
+func NowUnix() int64 {
+	return now().Unix()
+}
+
+// Today return the current date in the given time zone using DefaultDateLayout,
+// the time zone is default to the local time zone. An error is returned if the time zone is invalid.
+func Today(zone ...string) (string, error) {
+	t, err := nowIn(zone...)
+	if err != nil {
+		return "", err
 	}
+	return t.Format(DefaultDateLayout), nil
+}
+
+// nowIn return the current time in the given time zone, the local time zone is used if not provided.
+func nowIn(zone ...string) (time.Time, error) {
+	loc := time.Local
+	if len(zone) > 0 && zone[0] != "" {
+		l, err := time.LoadLocation(zone[0])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone: %s, err: %w", zone[0], err)
+		}
+		loc = l
+	}
+	return now().In(loc), nil
 }
 
 // FormatTime format the given date
 //
 // Date can be a `time.Time` or an `int, int32, int64`.
 // In the later case, it is treated as seconds since UNIX
-// epoch.
+// epoch. Other types are treated as the current time.
 func FormatTime(fmt string, zone string, date interface{}) string {
 	if zone == "" {
 		zone = "Local"
@@ -25,28 +200,50 @@ func FormatTime(fmt string, zone string, date interface{}) string {
 }
 
 func formatDate(fmt string, date interface{}, zone string) string {
-	var t time.Time
+	t := toTime(date)
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		loc, _ = time.LoadLocation("UTC")
+	}
+
+	return t.In(loc).Format(fmt)
+}
+
+// toTime convert the given `time.Time` or `int, int32, int64` seconds since UNIX epoch to time.
+// Other types are treated as now.
+func toTime(date interface{}) time.Time {
 	switch date := date.(type) {
-	default:
-		t = time.Now()
 	case time.Time:
-		t = date
+		return date
 	case *time.Time:
-		t = *date
+		return *date
 	case int64:
-		t = time.Unix(date, 0)
+		return time.Unix(date, 0)
 	case int:
-		t = time.Unix(int64(date), 0)
+		return time.Unix(int64(date), 0)
 	case int32:
-		t = time.Unix(int64(date), 0)
+		return time.Unix(int64(date), 0)
 	}
+	return now()
+}
 
-	loc, err := time.LoadLocation(zone)
-	if err != nil {
-		loc, _ = time.LoadLocation("UTC")
-	}
+// Since return the duration elapsed since the given time.
+// It accepts the same inputs as FormatTime.
+func Since(t interface{}) time.Duration {
+	return now().Sub(toTime(t))
+}
 
-	return t.In(loc).Format(fmt)
+// Until return the duration until the given time.
+// It accepts the same inputs as FormatTime.
+func Until(t interface{}) time.Duration {
+	return toTime(t).Sub(now())
+}
+
+// TimeDiff return the duration a - b.
+// It accepts the same inputs as FormatTime.
+func TimeDiff(a, b interface{}) time.Duration {
+	return toTime(a).Sub(toTime(b))
 }
 
 func FormatDuration(v interface{}) string {
@@ -0,0 +1,202 @@
+package funcs_test
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strconv"
+	"testing"
+	"time"
+
+	tt "github.com/pthethanh/tiny/funcs"
+)
+
+func TestTimeDiff(t *testing.T) {
+	past := time.Now().Add(-2 * time.Hour)
+	future := time.Now().Add(3 * time.Hour)
+	within := func(min, max time.Duration) func(got string) error {
+		return func(got string) error {
+			d, err := strconv.ParseInt(got, 10, 64)
+			if err != nil {
+				return err
+			}
+			if time.Duration(d) < min || time.Duration(d) > max {
+				return fmt.Errorf("got duration=%v, want duration in [%v, %v]", time.Duration(d), min, max)
+			}
+			return nil
+		}
+	}
+	testIt(t, []testCase{
+		{
+			name:       "since past time",
+			template:   `{{since . | printf "%d"}}`,
+			data:       past,
+			verifyFunc: within(2*time.Hour, 2*time.Hour+time.Minute),
+		},
+		{
+			name:       "since past unix",
+			template:   `{{since . | printf "%d"}}`,
+			data:       past.Unix(),
+			verifyFunc: within(2*time.Hour-time.Second, 2*time.Hour+time.Minute),
+		},
+		{
+			name:       "until future time",
+			template:   `{{until_time . | printf "%d"}}`,
+			data:       &future,
+			verifyFunc: within(3*time.Hour-time.Minute, 3*time.Hour),
+		},
+		{
+			name:     "time diff mixed types",
+			template: `{{time_diff .a .b}}`,
+			data: map[string]interface{}{
+				"a": time.Unix(1620000000, 0),
+				"b": int64(1620000000 - 90),
+			},
+			output: "1m30s",
+		},
+		{
+			name:     "time diff negative with duration",
+			template: `{{time_diff .a .b | duration}}`,
+			data: map[string]interface{}{
+				"a": 1620000000,
+				"b": time.Unix(1620000000+3*3600, 0),
+			},
+			output: "",
+		},
+		{
+			name:     "time diff with duration",
+			template: `{{time_diff .a .b | duration}}`,
+			data: map[string]interface{}{
+				"a": int32(1620000000 + 3*3600),
+				"b": 1620000000,
+			},
+			output: "3 hours ",
+		},
+	})
+}
+
+func TestYearAndToday(t *testing.T) {
+	fixed := time.Date(2021, 12, 31, 20, 0, 0, 0, time.UTC)
+	defer tt.SetNow(func() time.Time { return fixed })()
+	testIt(t, []testCase{
+		{
+			name:     "year",
+			template: `{{year "UTC"}}`,
+			output:   "2021",
+		},
+		{
+			name:     "year in time zone",
+			template: `{{year "Asia/Ho_Chi_Minh"}}`,
+			output:   "2022",
+		},
+		{
+			name:     "today",
+			template: `{{today "UTC"}}`,
+			output:   "2021-12-31",
+		},
+		{
+			name:     "today in time zone",
+			template: `{{today "Asia/Ho_Chi_Minh"}}`,
+			output:   "2022-01-01",
+		},
+		{
+			name:     "now unix",
+			template: `{{now_unix}}`,
+			output:   strconv.FormatInt(fixed.Unix(), 10),
+		},
+	})
+	if got, err := tt.Year(); err != nil || got != fixed.In(time.Local).Year() {
+		t.Errorf("got year=%d, err=%v, want year=%d", got, err, fixed.In(time.Local).Year())
+	}
+	for _, tmpl := range []string{`{{year "Invalid/Zone"}}`, `{{today "Invalid/Zone"}}`} {
+		if err := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(tmpl)).Execute(&bytes.Buffer{}, nil); err == nil {
+			t.Errorf("%s: got no error, want error for invalid time zone", tmpl)
+		}
+	}
+}
+
+func TestTimeAgo(t *testing.T) {
+	fixed := time.Date(2021, 5, 20, 12, 0, 0, 0, time.UTC)
+	defer tt.SetNow(func() time.Time { return fixed })()
+	testIt(t, []testCase{
+		{name: "just now", template: `{{time_ago .}}`, data: fixed.Add(-30 * time.Second), output: "just now"},
+		{name: "a minute ago", template: `{{time_ago .}}`, data: fixed.Add(-time.Minute), output: "1 minute ago"},
+		{name: "minutes ago", template: `{{time_ago .}}`, data: fixed.Add(-45 * time.Minute), output: "45 minutes ago"},
+		{name: "hours ago", template: `{{time_ago .}}`, data: fixed.Add(-3 * time.Hour), output: "3 hours ago"},
+		{name: "a day ago unix", template: `{{time_ago .}}`, data: fixed.Add(-25 * time.Hour).Unix(), output: "1 day ago"},
+		{name: "days ago", template: `{{time_ago .}}`, data: fixed.Add(-29 * 24 * time.Hour), output: "29 days ago"},
+		{name: "in an hour", template: `{{time_ago .}}`, data: fixed.Add(time.Hour), output: "in 1 hour"},
+		{name: "in days", template: `{{time_ago .}}`, data: fixed.Add(50 * time.Hour), output: "in 2 days"},
+		{name: "absolute past", template: `{{time_ago .}}`, data: fixed.Add(-31 * 24 * time.Hour), output: "2021-04-19"},
+		{name: "absolute future", template: `{{time_ago .}}`, data: fixed.Add(40 * 24 * time.Hour), output: "2021-06-29"},
+	})
+}
+
+func TestFixedNow(t *testing.T) {
+	fixed := time.Date(2021, 5, 20, 12, 30, 0, 0, time.UTC)
+	defer tt.SetNow(func() time.Time { return fixed })()
+	testIt(t, []testCase{
+		{name: "format unknown type as now", template: `{{date "2006-01-02 15:04" "UTC" .}}`, data: "not a time", output: "2021-05-20 12:30"},
+		{name: "format nil as now", template: `{{date "Jan 2, 2006" "Asia/Ho_Chi_Minh" .}}`, output: "May 20, 2021"},
+		{name: "since", template: `{{since . | printf "%d"}}`, data: fixed.Add(-2 * time.Hour), output: strconv.FormatInt(int64(2*time.Hour), 10)},
+		{name: "until", template: `{{until_time . | printf "%d"}}`, data: fixed.Add(90 * time.Minute).Unix(), output: strconv.FormatInt(int64(90*time.Minute), 10)},
+	})
+}
+
+func TestParseTime(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "rfc3339",
+			template: `{{with parse_time "2006-01-02T15:04:05Z07:00" .}}{{date "2006-01-02 15:04" "Asia/Ho_Chi_Minh" .}}{{end}}`,
+			data:     "2021-05-20T12:30:00Z",
+			output:   "2021-05-20 19:30",
+		},
+		{
+			name:     "named layout",
+			template: `{{unix (parse_time "RFC3339" .)}}`,
+			data:     "2021-05-20T12:30:00+07:00",
+			output:   strconv.FormatInt(time.Date(2021, 5, 20, 5, 30, 0, 0, time.UTC).Unix(), 10),
+		},
+		{
+			name:     "compare dates",
+			template: `{{if lt (unix (parse_time "date" .)) (unix (parse_time "date" "2021-06-01"))}}before{{end}}`,
+			data:     "2021-05-20",
+			output:   "before",
+		},
+		{
+			name:     "unix of seconds",
+			template: `{{unix .}}`,
+			data:     int64(1621513800),
+			output:   "1621513800",
+		},
+	})
+}
+
+func TestParseTimeError(t *testing.T) {
+	tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(`{{parse_time "RFC3339" .}}`))
+	if err := tmpl.Execute(&bytes.Buffer{}, "2021-05-20"); err == nil {
+		t.Errorf("got err=nil, want error for bad input")
+	}
+}
+
+func TestDateArithmetic(t *testing.T) {
+	fixed := time.Date(2021, 1, 31, 22, 30, 0, 0, time.UTC)
+	defer tt.SetNow(func() time.Time { return fixed })()
+	testIt(t, []testCase{
+		{name: "now", template: `{{now | date "2006" "UTC"}}`, output: "2021"},
+		{name: "add date", template: `{{now | add_date 1 0 1 | date "2006-01-02" "UTC"}}`, output: "2022-02-01"},
+		{name: "add month normalized", template: `{{now | add_date 0 1 0 | date "2006-01-02" "UTC"}}`, output: "2021-03-03"},
+		{name: "sub date", template: `{{now | add_date 0 0 -31 | date "2006-01-02" "UTC"}}`, output: "2020-12-31"},
+		{name: "add time", template: `{{now | add_time "1h30m" | date "2006-01-02 15:04" "UTC"}}`, output: "2021-02-01 00:00"},
+		{name: "sub time", template: `{{now | sub_time "24h" | date "2006-01-02 15:04" "UTC"}}`, output: "2021-01-30 22:30"},
+		{name: "sub time of unix", template: `{{sub_time . 1612132200 | unix}}`, data: time.Hour, output: "1612128600"},
+		{name: "since sub time", template: `{{since (now | sub_time "2h") | printf "%d"}}`, output: strconv.FormatInt(int64(2*time.Hour), 10)},
+	})
+}
+
+func TestSubTimeError(t *testing.T) {
+	tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(`{{now | sub_time .}}`))
+	if err := tmpl.Execute(&bytes.Buffer{}, "one day"); err == nil {
+		t.Errorf("got err=nil, want error for invalid duration")
+	}
+}
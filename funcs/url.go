@@ -0,0 +1,153 @@
+package funcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type (
+	// Breadcrumb is an item of the breadcrumbs of a path.
+	Breadcrumb struct {
+		Name string
+		URL  string
+	}
+)
+
+// URLFuncMap return URL func map.
+func URLFuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"url_parse": URLParse,
+		"url_host":  URLHost,
+		"url_path":  URLPath,
+		"is_url":    IsURL,
+		// breadcrumbs
+		"breadcrumbs":       Breadcrumbs,
+		"breadcrumb_jsonld": BreadcrumbJSONLD,
+	}
+}
+
+// URLParse parse the given URL, the components can be accessed via
+// .Scheme, .Host, .Hostname, .Port, .Path, .Query, .RawQuery, .Fragment.
+func URLParse(s string) (*url.URL, error) {
+	return url.Parse(s)
+}
+
+// URLHost return host of the given URL, including port if any.
+func URLHost(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return u.Host, nil
+}
+
+// URLPath return path of the given URL.
+func URLPath(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return u.Path, nil
+}
+
+// IsURL report whether the given string is an absolute http or https URL with a host, i.e: https://example.com/blog.
+// URLs without scheme such as example.com are not accepted.
+func IsURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	return u.Hostname() != "" && !strings.ContainsAny(s, " \t\r\n")
+}
+
+// Breadcrumbs return the breadcrumbs of the given path or URL, starting from the home page, i.e:
+// /blog/my-post gives Home (/), Blog (/blog), My post (/blog/my-post).
+// The names are derived from the path segments, they can be overridden by the labels
+// keyed by the segment path or the segment itself, i.e: dict "/blog" "Articles".
+// The URLs are absolute if the given URL is.
+func Breadcrumbs(pth string, labels ...map[string]interface{}) ([]Breadcrumb, error) {
+	u, err := url.Parse(pth)
+	if err != nil {
+		return nil, err
+	}
+	base := ""
+	if u.Scheme != "" && u.Host != "" {
+		base = u.Scheme + "://" + u.Host
+	}
+	label := func(p, seg, df string) string {
+		for _, m := range labels {
+			for _, k := range []string{p, seg} {
+				if v, ok := m[k]; ok && k != "" {
+					return fmt.Sprintf("%v", v)
+				}
+			}
+		}
+		return df
+	}
+	crumbs := []Breadcrumb{{Name: label("/", "", "Home"), URL: base + "/"}}
+	p := ""
+	for _, seg := range strings.Split(u.Path, "/") {
+		if seg == "" {
+			continue
+		}
+		p += "/" + seg
+		name, err := url.PathUnescape(seg)
+		if err != nil {
+			name = seg
+		}
+		crumbs = append(crumbs, Breadcrumb{Name: label(p, seg, segmentName(name)), URL: base + p})
+	}
+	return crumbs, nil
+}
+
+// BreadcrumbJSONLD return the schema.org BreadcrumbList JSON-LD script of the breadcrumbs
+// of the given path or URL, see Breadcrumbs. Search engines require absolute URLs for the items,
+// hence a full URL should be given, i.e: breadcrumb_jsonld (abs_url .URL.Path) (dict "/blog" "Articles").
+func BreadcrumbJSONLD(pth string, labels ...map[string]interface{}) (template.HTML, error) {
+	crumbs, err := Breadcrumbs(pth, labels...)
+	if err != nil {
+		return "", err
+	}
+	type listItem struct {
+		Type     string `json:"@type"`
+		Position int    `json:"position"`
+		Name     string `json:"name"`
+		Item     string `json:"item"`
+	}
+	items := make([]listItem, 0, len(crumbs))
+	for i, c := range crumbs {
+		items = append(items, listItem{Type: "ListItem", Position: i + 1, Name: c.Name, Item: c.URL})
+	}
+	// json.Marshal escapes <, > and &, so it's safe to be embedded in the script.
+	b, err := json.Marshal(struct {
+		Context string     `json:"@context"`
+		Type    string     `json:"@type"`
+		Items   []listItem `json:"itemListElement"`
+	}{
+		Context: "https://schema.org",
+		Type:    "BreadcrumbList",
+		Items:   items,
+	})
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(`<script type="application/ld+json">` + string(b) + `</script>`), nil
+}
+
+// segmentName return the human readable name of a path segment, i.e: my-post gives My post.
+func segmentName(seg string) string {
+	name := strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(seg))
+	r, n := utf8.DecodeRuneInString(name)
+	if n == 0 {
+		return seg
+	}
+	return string(unicode.ToUpper(r)) + name[n:]
+}
@@ -0,0 +1,141 @@
+package funcs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"testing"
+
+	tt "github.com/pthethanh/tiny/funcs"
+)
+
+func TestURL(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "full url",
+			template: `{{with url_parse .}}{{.Scheme}}|{{.Host}}|{{.Hostname}}|{{.Port}}|{{.Path}}|{{.Query.Get "q"}}|{{.Fragment}}{{end}}`,
+			data:     "https://example.com:8080/blog/post?q=go&page=2#comments",
+			output:   "https|example.com:8080|example.com|8080|/blog/post|go|comments",
+		},
+		{
+			name:     "relative path",
+			template: `{{with url_parse .}}{{.Scheme}}|{{.Host}}|{{.Path}}|{{.RawQuery}}{{end}}`,
+			data:     "/blog/post?page=2",
+			output:   "||/blog/post|page=2",
+		},
+		{
+			name:     "host",
+			template: `{{url_host .}}`,
+			data:     "https://example.com/blog",
+			output:   "example.com",
+		},
+		{
+			name:     "path",
+			template: `{{url_path .}}`,
+			data:     "https://example.com/blog/post?x=1",
+			output:   "/blog/post",
+		},
+	})
+}
+
+func TestURLParseError(t *testing.T) {
+	tmpl := template.Must(template.New("").Funcs(tt.FuncMap()).Parse(`{{url_host .}}`))
+	if err := tmpl.Execute(&bytes.Buffer{}, "http://[::1"); err == nil {
+		t.Errorf("got err=nil, want error for malformed URL")
+	}
+}
+
+func TestBreadcrumbs(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "relative path",
+			template: `{{range breadcrumbs .}}{{.Name}}={{.URL}};{{end}}`,
+			data:     "/blog/my-first_post",
+			output:   "Home=/;Blog=/blog;My first post=/blog/my-first_post;",
+		},
+		{
+			name:     "labels",
+			template: `{{range breadcrumbs . (dict "/" "Start" "blog" "Articles")}}{{.Name}}={{.URL}};{{end}}`,
+			data:     "https://example.com/blog/",
+			output:   "Start=https://example.com/;Articles=https://example.com/blog;",
+		},
+	})
+}
+
+func TestBreadcrumbJSONLD(t *testing.T) {
+	testIt(t, []testCase{
+		{
+			name:     "json-ld",
+			template: `{{breadcrumb_jsonld . (dict "/blog" "Articles")}}`,
+			data:     "https://example.com/blog/go-tips",
+			verifyFunc: func(s string) error {
+				const prefix, suffix = `<script type="application/ld+json">`, `</script>`
+				if !strings.HasPrefix(s, prefix) || !strings.HasSuffix(s, suffix) {
+					return fmt.Errorf("got %s, want a JSON-LD script", s)
+				}
+				v := struct {
+					Context string `json:"@context"`
+					Type    string `json:"@type"`
+					Items   []struct {
+						Type     string `json:"@type"`
+						Position int    `json:"position"`
+						Name     string `json:"name"`
+						Item     string `json:"item"`
+					} `json:"itemListElement"`
+				}{}
+				if err := json.Unmarshal([]byte(strings.TrimSuffix(strings.TrimPrefix(s, prefix), suffix)), &v); err != nil {
+					return err
+				}
+				if v.Context != "https://schema.org" || v.Type != "BreadcrumbList" {
+					return fmt.Errorf("got context=%s, type=%s, want a schema.org BreadcrumbList", v.Context, v.Type)
+				}
+				want := []struct{ name, item string }{
+					{"Home", "https://example.com/"},
+					{"Articles", "https://example.com/blog"},
+					{"Go tips", "https://example.com/blog/go-tips"},
+				}
+				if len(v.Items) != len(want) {
+					return fmt.Errorf("got %d items, want %d items", len(v.Items), len(want))
+				}
+				for i, item := range v.Items {
+					if item.Type != "ListItem" || item.Position != i+1 || item.Name != want[i].name || item.Item != want[i].item {
+						return fmt.Errorf("got item=%+v, want position=%d, name=%s, item=%s", item, i+1, want[i].name, want[i].item)
+					}
+				}
+				return nil
+			},
+		},
+	})
+}
+
+func TestIsURL(t *testing.T) {
+	cases := []struct {
+		url   string
+		valid bool
+	}{
+		{url: "https://example.com", valid: true},
+		{url: "http://example.com:8080/blog?q=go#top", valid: true},
+		{url: "https://sub.example.com/a/b", valid: true},
+		{url: "example.com", valid: false},
+		{url: "//example.com/blog", valid: false},
+		{url: "/blog/post", valid: false},
+		{url: "ftp://example.com/file", valid: false},
+		{url: "javascript:alert(1)", valid: false},
+		{url: "https://", valid: false},
+		{url: "https://exa mple.com", valid: false},
+		{url: "http://[::1", valid: false},
+		{url: "", valid: false},
+	}
+	tcs := make([]testCase, 0, len(cases))
+	for _, c := range cases {
+		tcs = append(tcs, testCase{
+			name:     "is_url " + c.url,
+			template: `{{is_url .}}`,
+			data:     c.url,
+			output:   fmt.Sprintf("%v", c.valid),
+		})
+	}
+	testIt(t, tcs)
+}
@@ -1,19 +1,28 @@
 package tiny
 
 import (
-	"log"
+	"bytes"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 type (
 	StaticSite struct {
 		Enable       bool          `yaml:"enable"`
-		Output       StaticOutput  `yaml:"output"`
+		Output       StaticOutputs `yaml:"output"`
 		Static       []string      `yaml:"static"`
 		AllowedPages []string      `yaml:"allowed_pages"`
 		Request      StaticRequest `yaml:"request"`
@@ -23,38 +32,114 @@ type (
 		RootDir   string   `yaml:"root_dir"`
 		StaticDir string   `yaml:"static_dir"`
 		Keep      []string `yaml:"keep"`
+		// RewriteBaseURL replaces the site base_url in the generated pages if provided.
+		RewriteBaseURL string `yaml:"rewrite_base_url"`
 	}
 
+	// StaticOutputs is the list of output targets of the static site,
+	// pages are rendered once and written to each of the targets.
+	StaticOutputs []StaticOutput
+
 	StaticRequest struct {
-		Host                 string   `yaml:"host"`
-		Paths                []string `yaml:"paths"`
+		// Host is the base URL of the running site to request the pages from, i.e: http://localhost:8000.
+		Host string `yaml:"host"`
+		// AllowedHosts are the host names which can be requested, default to the loopback hosts.
+		AllowedHosts []string `yaml:"allowed_hosts"`
+		// Concurrency is the number of pages are requested concurrently, default to GOMAXPROCS.
+		Concurrency int `yaml:"concurrency"`
+		// Headers and Cookies are sent with the requests, i.e: for generating from an auth-protected staging.
+		// Environment variables in the values are expanded, i.e: Authorization: Bearer ${STAGING_TOKEN}.
+		Headers              map[string]string `yaml:"headers"`
+		Cookies              map[string]string `yaml:"cookies"`
+		Paths                []string          `yaml:"paths"`
 		dynamicPathsHandlers []DynamicPathsHandler
 	}
 
 	ResponseWriter struct {
 		http.ResponseWriter
-		body []byte
+		body   []byte
+		status int
 	}
 
 	DynamicPathsHandler = func() []string
+
+	// staticErrors is the errors of the failed paths of the static site generation.
+	staticErrors []error
+)
+
+const (
+	// staticGeneratorHeader marks the requests of the static generator.
+	staticGeneratorHeader = "X-Tiny-Static-Generator"
+)
+
+var (
+	defaultAllowedHosts = []string{"localhost", "127.0.0.1", "::1"}
 )
 
+func (w *ResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
 func (w *ResponseWriter) Write(b []byte) (int, error) {
 	w.body = append(w.body, b...)
 	return w.ResponseWriter.Write(b)
 }
 
+func (errs staticErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
 func (site *Site) AddDynamicPathsHandlers(hs ...DynamicPathsHandler) {
+	site.mu.Lock()
+	defer site.mu.Unlock()
 	site.StaticSite.Request.dynamicPathsHandlers = append(site.StaticSite.Request.dynamicPathsHandlers, hs...)
+	if site.next != nil {
+		// the site was reloaded, the new config generates the static site.
+		site.next.StaticSite.Request.dynamicPathsHandlers = site.StaticSite.Request.dynamicPathsHandlers
+	}
+}
+
+// UnmarshalYAML accepts either a single output target or a list of output targets.
+func (outputs *StaticOutputs) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		targets := []StaticOutput{}
+		if err := value.Decode(&targets); err != nil {
+			return err
+		}
+		*outputs = targets
+		return nil
+	}
+	target := StaticOutput{}
+	if err := value.Decode(&target); err != nil {
+		return err
+	}
+	*outputs = StaticOutputs{target}
+	return nil
 }
 
 func (site *Site) prepareStaticSite() error {
-	files, err := os.ReadDir(site.StaticSite.Output.RootDir)
+	for _, output := range site.StaticSite.Output {
+		if err := site.prepareStaticOutput(output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (site *Site) prepareStaticOutput(output StaticOutput) error {
+	files, err := os.ReadDir(output.RootDir)
 	if err != nil {
 		return err
 	}
 	keeps := map[string]bool{}
-	for _, k := range site.StaticSite.Output.Keep {
+	for _, k := range output.Keep {
 		keeps[k] = true
 	}
 	// clean up old files
@@ -62,7 +147,7 @@ func (site *Site) prepareStaticSite() error {
 		if keeps[f.Name()] {
 			continue
 		}
-		pth := filepath.Join(site.StaticSite.Output.RootDir, f.Name())
+		pth := filepath.Join(output.RootDir, f.Name())
 		if err := os.RemoveAll(pth); err != nil {
 			return err
 		}
@@ -74,11 +159,11 @@ func (site *Site) prepareStaticSite() error {
 			return err
 		}
 		if ff.IsDir() {
-			if err := copyDir(f, site.StaticSite.Output.StaticDir); err != nil {
+			if err := copyDir(f, output.StaticDir); err != nil {
 				return err
 			}
 		} else {
-			if err := copyFile(f, filepath.Join(site.StaticSite.Output.StaticDir, filepath.Base(f))); err != nil {
+			if err := copyFile(f, filepath.Join(output.StaticDir, filepath.Base(f))); err != nil {
 				return err
 			}
 		}
@@ -89,80 +174,296 @@ func (site *Site) prepareStaticSite() error {
 func (site *Site) staticGeneratorHandler() func(h http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// only capture body of the generated pages, other responses such as static assets are served as is.
+			if !site.isGeneratedPage(r.URL.Path) {
+				h.ServeHTTP(w, r)
+				return
+			}
 			mw := &ResponseWriter{
 				ResponseWriter: w,
 			}
+			if r.Header.Get(staticGeneratorHeader) != "" {
+				r = withoutCSPNonce(r)
+			}
 			h.ServeHTTP(mw, r)
-			for _, page := range site.StaticSite.AllowedPages {
-				if ok, _ := regexp.MatchString(page, r.URL.Path); ok {
-					dir := path.Dir(r.URL.Path)
-					name := path.Base(r.URL.Path)
-					sep := "/"
-					if dir == sep {
-						if name == sep || name == "" {
-							dir = ""
-							name = "index.html"
-						} else {
-							dir = ""
-							if filepath.Ext(name) == "" {
-								name = name + ".html"
-							}
-						}
-					} else {
-						if name == sep || name == "" {
-							dir = r.URL.Path
-							name = "index.html"
-						} else {
-							if filepath.Ext(name) == "" {
-								name = name + ".html"
-							}
-						}
-					}
-					dir = filepath.Join(site.StaticSite.Output.RootDir, dir)
-					pth := filepath.Join(dir, name)
-					if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-						log.Printf("error: %v", err)
-						return
-					}
-					if dir == "" {
-						pth = name
-					}
-					f, err := os.Create(pth)
-					if err != nil {
-						log.Printf("error: %v", err)
-						return
-					}
-					defer f.Close()
-					if _, err := f.Write(mw.body); err != nil {
-						log.Printf("error: write static file failed, err: %v", err)
-					}
+			// the error responses are reported by the generator instead of being written as the pages.
+			if mw.status != 0 && (mw.status < 200 || mw.status > 299) {
+				return
+			}
+			for _, output := range site.StaticSite.Output {
+				if err := site.writeStaticFile(output, outputPathFor(r.URL.Path, output), mw.body); err != nil {
+					site.logger.Errorf("write static file failed, err: %v", err)
 				}
 			}
 		})
 	}
 }
 
+// outputPathFor return path of the generated file of the given URL path in the output target:
+//   - paths end with "/" are written as index.html of the directory, i.e: /blog/ -> blog/index.html.
+//   - paths without extension are written with .html extension, i.e: /blog/post -> blog/post.html.
+//   - paths with extension are written as is, i.e: /feed.xml -> feed.xml.
+func outputPathFor(urlPath string, output StaticOutput) string {
+	dir, name := path.Clean("/"+urlPath), "index.html"
+	if dir != "/" && !strings.HasSuffix(urlPath, "/") {
+		dir, name = path.Split(dir)
+		if path.Ext(name) == "" {
+			name = name + ".html"
+		}
+	}
+	return filepath.Join(output.RootDir, filepath.FromSlash(strings.TrimPrefix(dir, "/")), name)
+}
+
+// isGeneratedPage report whether the given path matches one of the allowed pages of the static site.
+func (site *Site) isGeneratedPage(pth string) bool {
+	for _, page := range site.StaticSite.AllowedPages {
+		if ok, _ := regexp.MatchString(page, pth); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// writeStaticFile write the rendered body of a page into the given path of the output target.
+// The writes are locked per path since different URL paths can be generated into the same file, i.e: /blog and /blog.html.
+func (site *Site) writeStaticFile(output StaticOutput, pth string, body []byte) error {
+	lock, _ := site.fileLocks.LoadOrStore(pth, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+	if err := os.MkdirAll(filepath.Dir(pth), os.ModePerm); err != nil {
+		return err
+	}
+	if baseURL := site.MetaData.BaseURL(); baseURL != "" && output.RewriteBaseURL != "" {
+		body = bytes.ReplaceAll(body, []byte(baseURL), []byte(output.RewriteBaseURL))
+	}
+	f, err := os.Create(pth)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(body); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateHost validate the host of the requests is provided and allowed.
+func (req StaticRequest) validateHost(host string) error {
+	u, err := url.Parse(host)
+	if err != nil {
+		return fmt.Errorf("invalid static site request host: %s, err: %w", host, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid static site request host: %q, must be an http(s) URL", host)
+	}
+	allowed := req.AllowedHosts
+	if len(allowed) == 0 {
+		allowed = defaultAllowedHosts
+	}
+	for _, h := range allowed {
+		if strings.EqualFold(h, u.Hostname()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("static site request host: %s is not allowed, allowed hosts: %v", u.Hostname(), allowed)
+}
+
+// newRequest create the request of the given path with the configured headers and cookies.
+func (req StaticRequest) newRequest(p string) (*http.Request, error) {
+	r, err := http.NewRequest(http.MethodGet, req.Host+p, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set(staticGeneratorHeader, "1")
+	for k, v := range req.Headers {
+		r.Header.Set(k, os.ExpandEnv(v))
+	}
+	for k, v := range req.Cookies {
+		r.AddCookie(&http.Cookie{Name: k, Value: os.ExpandEnv(v)})
+	}
+	return r, nil
+}
+
 func (site *Site) GenerateStaticSite() error {
+	if cur := site.current(); cur != site {
+		return cur.GenerateStaticSite()
+	}
 	if !site.StaticSite.Enable {
-		log.Println("warning: static site is disabled")
+		site.logger.Infof("static site is disabled")
 		return nil
 	}
+	req := site.StaticSite.Request
+	if err := req.validateHost(req.Host); err != nil {
+		return err
+	}
 	if err := site.prepareStaticSite(); err != nil {
-		log.Printf("error: failed to prepare static site, err: %v", err)
+		site.logger.Errorf("failed to prepare static site, err: %v", err)
 	}
-	paths := site.StaticSite.Request.Paths
-	for _, h := range site.StaticSite.Request.dynamicPathsHandlers {
+	paths := req.Paths
+	for _, h := range req.dynamicPathsHandlers {
 		paths = append(paths, h()...)
 	}
 	c := http.Client{
 		Timeout: 60 * time.Second,
+		// don't follow redirects to the hosts which are not allowed.
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return req.validateHost(r.URL.Scheme + "://" + r.URL.Host)
+		},
 	}
 	defer c.CloseIdleConnections()
+	if err := req.fetchAll(&c, uniquePaths(paths)); err != nil {
+		return err
+	}
+	return site.generateSiteFiles(&c, paths)
+}
+
+// fetchAll request the given paths using a pool of workers, the errors of all the failed paths are returned.
+func (req StaticRequest) fetchAll(c *http.Client, paths []string) error {
+	n := req.Concurrency
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if n > len(paths) {
+		n = len(paths)
+	}
+	jobs := make(chan int)
+	results := make([]error, len(paths))
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				// read the whole response, so that the page is completely generated.
+				if _, err := req.fetch(c, paths[j]); err != nil {
+					results[j] = fmt.Errorf("path: %s, err: %w", paths[j], err)
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	errs := staticErrors{}
+	for _, err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errs
+}
+
+// uniquePaths return the given paths without duplicates, so that a file is not generated concurrently.
+func uniquePaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	rs := make([]string, 0, len(paths))
 	for _, p := range paths {
-		_, err := c.Get(site.StaticSite.Request.Host + p)
+		if !seen[p] {
+			seen[p] = true
+			rs = append(rs, p)
+		}
+	}
+	return rs
+}
+
+// fetch request the given path and return the body of the response, non-2xx responses are errors.
+func (req StaticRequest) fetch(c *http.Client, p string) ([]byte, error) {
+	r, err := req.newRequest(p)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// generateSiteFiles write sitemap.xml and robots.txt to the root dir of the output targets.
+// The sitemap and robots.txt pages are rendered by the site if configured,
+// otherwise the sitemap is generated from the generated paths.
+func (site *Site) generateSiteFiles(c *http.Client, paths []string) error {
+	req := site.StaticSite.Request
+	files := map[string]string{
+		PageSitemapXML: site.siteMapPath(),
+		PageRobotsTxt:  site.robotsTXTPath(),
+	}
+	for name, pth := range files {
+		if pth == "" || strings.Contains(pth, "{") {
+			continue
+		}
+		body, err := req.fetch(c, pth)
 		if err != nil {
 			return err
 		}
+		for _, output := range site.StaticSite.Output {
+			if err := site.writeStaticFile(output, filepath.Join(output.RootDir, name), body); err != nil {
+				return err
+			}
+		}
+	}
+	if files[PageSitemapXML] != "" {
+		return nil
+	}
+	for _, output := range site.StaticSite.Output {
+		body, err := site.generatedSiteMap(output, paths)
+		if err != nil {
+			return err
+		}
+		if body == nil {
+			continue
+		}
+		if err := site.writeStaticFile(output, filepath.Join(output.RootDir, PageSitemapXML), body); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// generatedSiteMap return the sitemap of the generated HTML pages of the given paths,
+// or nil if there is no base URL for the absolute URLs of the sitemap.
+func (site *Site) generatedSiteMap(output StaticOutput, paths []string) ([]byte, error) {
+	base := site.MetaData.BaseURL()
+	if base == "" {
+		base = output.RewriteBaseURL
+	}
+	if base == "" {
+		site.logger.Infof("skip generating sitemap for %s, base_url is not provided", output.RootDir)
+		return nil, nil
+	}
+	seen := map[string]bool{}
+	urls := make([]SiteMapURL, 0, len(paths))
+	for _, p := range paths {
+		if seen[p] || !site.isGeneratedPage(p) {
+			continue
+		}
+		seen[p] = true
+		if ext := path.Ext(p); ext != "" && ext != ".html" {
+			continue
+		}
+		urls = append(urls, SiteMapURL{Loc: absURL(base, p)})
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].Loc < urls[j].Loc
+	})
+	return SiteMap{URLSet: urls}.Marshal()
+}
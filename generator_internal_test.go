@@ -0,0 +1,39 @@
+package tiny
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputPathFor(t *testing.T) {
+	cases := []struct {
+		urlPath string
+		rootDir string
+		want    string
+	}{
+		{urlPath: "", rootDir: "public", want: "public/index.html"},
+		{urlPath: "/", rootDir: "public", want: "public/index.html"},
+		{urlPath: "/", rootDir: "", want: "index.html"},
+		{urlPath: "/about", rootDir: "public", want: "public/about.html"},
+		{urlPath: "/about", rootDir: "", want: "about.html"},
+		{urlPath: "/about/", rootDir: "public", want: "public/about/index.html"},
+		{urlPath: "/feed.xml", rootDir: "public", want: "public/feed.xml"},
+		{urlPath: "/blog/post", rootDir: "public", want: "public/blog/post.html"},
+		{urlPath: "/blog/post/", rootDir: "public", want: "public/blog/post/index.html"},
+		{urlPath: "/blog/2021/05/post", rootDir: "public", want: "public/blog/2021/05/post.html"},
+		{urlPath: "/blog/2021/05/", rootDir: "public", want: "public/blog/2021/05/index.html"},
+		{urlPath: "/blog/post.json", rootDir: "public", want: "public/blog/post.json"},
+		{urlPath: "/blog//post", rootDir: "public", want: "public/blog/post.html"},
+		{urlPath: "/blog/../../etc/passwd", rootDir: "public", want: "public/etc/passwd.html"},
+		{urlPath: "/v1.2/docs", rootDir: "public", want: "public/v1.2/docs.html"},
+		{urlPath: "/docs/v1.2", rootDir: "public", want: "public/docs/v1.2"},
+	}
+	for _, c := range cases {
+		t.Run(c.urlPath, func(t *testing.T) {
+			got := outputPathFor(c.urlPath, StaticOutput{RootDir: c.rootDir})
+			if want := filepath.FromSlash(c.want); got != want {
+				t.Errorf("got path=%s, want path=%s", got, want)
+			}
+		})
+	}
+}
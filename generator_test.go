@@ -0,0 +1,325 @@
+package tiny_test
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestGenerateStaticSiteMultipleOutputs(t *testing.T) {
+	site := newTestSite(t, `
+metadata:
+  base_url: http://localhost:8000
+pages:
+  index:
+    path: /
+    components: [index.html]
+  about:
+    path: /about
+    components: [about.html]
+static_site:
+  enable: true
+  allowed_pages: [".*"]
+  output:
+    - root_dir: cdn
+      rewrite_base_url: https://cdn.example.com
+    - root_dir: preview
+      rewrite_base_url: https://preview.example.com
+  request:
+    paths: [/, /about]
+`, map[string]string{
+		"index.html":    `<a href="[[.MetaData.BaseURL]]/about">about</a>`,
+		"about.html":    `about`,
+		"cdn/.keep":     ``,
+		"preview/.keep": ``,
+	})
+	srv := httptest.NewServer(site)
+	defer srv.Close()
+	site.StaticSite.Request.Host = srv.URL
+	if err := site.GenerateStaticSite(); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		file string
+		want string
+	}{
+		{file: "cdn/index.html", want: `href="https://cdn.example.com/about"`},
+		{file: "preview/index.html", want: `href="https://preview.example.com/about"`},
+		{file: "cdn/about.html", want: "about"},
+		{file: "preview/about.html", want: "about"},
+	}
+	for _, c := range cases {
+		b, err := os.ReadFile(filepath.FromSlash(c.file))
+		if err != nil {
+			t.Errorf("file: %s, err: %v", c.file, err)
+			continue
+		}
+		if !strings.Contains(string(b), c.want) {
+			t.Errorf("file: %s, got content=%s, want content contains %s", c.file, b, c.want)
+		}
+	}
+}
+
+func TestGenerateStaticSiteRequest(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+static_site:
+  enable: true
+  allowed_pages: [".*"]
+  output:
+    root_dir: public
+  request:
+    paths: [/]
+    headers:
+      Authorization: Bearer ${TINY_TEST_TOKEN}
+    cookies:
+      session: abc
+`, map[string]string{
+		"index.html":   `index`,
+		"public/.keep": ``,
+	})
+	t.Setenv("TINY_TEST_TOKEN", "secret")
+	var auth, cookie string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if ck, err := r.Cookie("session"); err == nil {
+			cookie = ck.Value
+		}
+		site.ServeHTTP(rw, r)
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name         string
+		host         string
+		allowedHosts []string
+		err          string
+	}{
+		{name: "missing host", host: "", err: "must be an http(s) URL"},
+		{name: "invalid scheme", host: "ftp://localhost", err: "must be an http(s) URL"},
+		{name: "host not allowed", host: "http://example.com", err: "example.com is not allowed"},
+		{name: "host not in allow list", host: srv.URL, allowedHosts: []string{"staging.example.com"}, err: "is not allowed"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			site.StaticSite.Request.Host = c.host
+			site.StaticSite.Request.AllowedHosts = c.allowedHosts
+			err := site.GenerateStaticSite()
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("got err=%v, want err contains %s", err, c.err)
+			}
+		})
+	}
+
+	site.StaticSite.Request.Host = srv.URL
+	site.StaticSite.Request.AllowedHosts = nil
+	if err := site.GenerateStaticSite(); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer secret" || cookie != "abc" {
+		t.Errorf("got Authorization=%s, cookie=%s, want the configured auth header and cookie", auth, cookie)
+	}
+	if b, err := os.ReadFile(filepath.Join("public", "index.html")); err != nil || string(b) != "index" {
+		t.Errorf("got content=%s, err=%v, want generated index", b, err)
+	}
+}
+
+func TestGenerateStaticSiteFiles(t *testing.T) {
+	type urlset struct {
+		URLs []struct {
+			Loc string `xml:"loc"`
+		} `xml:"url"`
+	}
+	cases := []struct {
+		name    string
+		config  string
+		sitemap []string
+		robots  string
+	}{
+		{
+			name: "generated sitemap",
+			config: `
+pages:
+  robots:
+    path: /robots.txt
+    data_type: robots
+`,
+			sitemap: []string{"https://example.com/", "https://example.com/about"},
+			robots:  "User-agent: *\nDisallow:\n",
+		},
+		{
+			name: "configured sitemap",
+			config: `
+pages:
+  robots.txt:
+    path: /robots.txt
+    data_type: robots
+  sitemap:
+    path: /sitemap.xml
+    data_type: sitemap
+`,
+			sitemap: []string{"https://example.com/", "https://example.com/about", "https://example.com/contact"},
+			robots:  "Sitemap: https://example.com/sitemap.xml",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			site := newTestSite(t, c.config+`
+  index:
+    path: /
+    components: [index.html]
+  about:
+    path: /about
+    components: [index.html]
+  contact:
+    path: /contact
+    components: [index.html]
+metadata:
+  base_url: https://example.com
+static_site:
+  enable: true
+  allowed_pages: ["^/$", "^/about$"]
+  output:
+    root_dir: public
+  request:
+    paths: [/, /about, /about]
+`, map[string]string{
+				"index.html":   `index`,
+				"public/.keep": ``,
+			})
+			srv := httptest.NewServer(site)
+			defer srv.Close()
+			site.StaticSite.Request.Host = srv.URL
+			if err := site.GenerateStaticSite(); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(filepath.Join("public", "sitemap.xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sitemap := urlset{}
+			if err := xml.Unmarshal(b, &sitemap); err != nil {
+				t.Fatalf("invalid sitemap: %s, err: %v", b, err)
+			}
+			locs := []string{}
+			for _, u := range sitemap.URLs {
+				locs = append(locs, u.Loc)
+			}
+			if strings.Join(locs, ",") != strings.Join(c.sitemap, ",") {
+				t.Errorf("got sitemap urls=%v, want sitemap urls=%v", locs, c.sitemap)
+			}
+			b, err = os.ReadFile(filepath.Join("public", "robots.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), c.robots) {
+				t.Errorf("got robots.txt=%q, want robots.txt contains %q", b, c.robots)
+			}
+		})
+	}
+}
+
+func TestGenerateStaticSiteConcurrency(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  post:
+    path: /posts/{id}
+    components: [post.html]
+static_site:
+  enable: true
+  allowed_pages: ["^/posts/"]
+  output:
+    - root_dir: public
+    - root_dir: preview
+  request:
+    concurrency: 8
+`, map[string]string{
+		"post.html":     `post [[.Vars.id]]`,
+		"public/.keep":  ``,
+		"preview/.keep": ``,
+	})
+	paths := []string{}
+	for i := 0; i < 100; i++ {
+		paths = append(paths, fmt.Sprintf("/posts/%d", i))
+	}
+	site.AddDynamicPathsHandlers(func() []string {
+		return paths
+	})
+	srv := httptest.NewServer(site)
+	defer srv.Close()
+	site.StaticSite.Request.Host = srv.URL
+	if err := site.GenerateStaticSite(); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"public", "preview"} {
+		for _, p := range paths {
+			f := filepath.Join(dir, filepath.FromSlash(p)+".html")
+			b, err := os.ReadFile(f)
+			if err != nil {
+				t.Errorf("file: %s, err: %v", f, err)
+				continue
+			}
+			if want := "post " + path.Base(p); string(b) != want {
+				t.Errorf("file: %s, got content=%s, want content=%s", f, b, want)
+			}
+		}
+	}
+}
+
+func TestGenerateStaticSiteErrors(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+  sitemap:
+    path: /sitemap.xml
+    data_type: sitemap
+    auth: true
+    auth_mode: status
+static_site:
+  enable: true
+  allowed_pages: [".*"]
+  output:
+    root_dir: public
+  request:
+    paths: [/, /missing, /broken]
+`, map[string]string{
+		"index.html":   `index`,
+		"public/.keep": ``,
+	}, tiny.AuthInfo(func(ctx context.Context) (interface{}, bool) {
+		return nil, false
+	}))
+	srv := httptest.NewServer(site)
+	defer srv.Close()
+	site.StaticSite.Request.Host = srv.URL
+	err := site.GenerateStaticSite()
+	if err == nil {
+		t.Fatal("got err=nil, want errors of the failed paths")
+	}
+	for _, want := range []string{"path: /missing, err: unexpected status code: 404", "path: /broken, err: unexpected status code: 404"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("got err=%v, want err contains %s", err, want)
+		}
+	}
+	// the sitemap is not written if the sitemap page fails.
+	site.StaticSite.Request.Paths = []string{"/"}
+	if err := site.GenerateStaticSite(); err == nil || !strings.Contains(err.Error(), "unexpected status code: 401") {
+		t.Errorf("got err=%v, want error of the sitemap page", err)
+	}
+	if _, err := os.Stat(filepath.Join("public", "sitemap.xml")); !os.IsNotExist(err) {
+		t.Errorf("got err=%v, want sitemap.xml not written", err)
+	}
+}
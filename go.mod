@@ -3,10 +3,15 @@ module github.com/pthethanh/tiny
 go 1.16
 
 require (
+	github.com/fsnotify/fsnotify v1.5.4
 	github.com/google/uuid v1.2.0
 	github.com/gorilla/mux v1.8.0
 	github.com/kr/text v0.2.0 // indirect
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
+	github.com/yuin/goldmark v1.4.13
+	golang.org/x/net v0.0.0-20210520170846-37e1c6afe023
+	golang.org/x/text v0.3.6
+	golang.org/x/time v0.0.0-20210220033141-f8bda1e9f3ba
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f // indirect
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
@@ -0,0 +1,136 @@
+package tiny
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// hreflangDefault is the hreflang value of the fallback page for unmatched languages.
+	hreflangDefault = "x-default"
+)
+
+type (
+	// Language is a language variant of the site, the pages of the language
+	// are served under the path prefix, i.e: /vi/about for prefix /vi.
+	// The default language usually has no prefix.
+	Language struct {
+		Code   string `yaml:"code"`
+		Prefix string `yaml:"prefix"`
+	}
+
+	// Alternate is the URL of a language variant of the current page.
+	Alternate struct {
+		Lang string
+		URL  string
+	}
+)
+
+// Hreflang return the alternate link tags of the page, i.e:
+// <link rel="alternate" hreflang="vi" href="https://example.com/vi/about">.
+func (page PageData) Hreflang() template.HTML {
+	b := &strings.Builder{}
+	for _, alt := range page.Alternates {
+		fmt.Fprintf(b, "<link rel=\"alternate\" hreflang=\"%s\" href=\"%s\">\n", html.EscapeString(alt.Lang), html.EscapeString(alt.URL))
+	}
+	return template.HTML(b.String())
+}
+
+// getAlternates return the URLs of the language variants of the request path,
+// including the x-default one. Only the variants served by the site are returned,
+// and nothing is returned if the page is not translated.
+func (site *Site) getAlternates(r *http.Request, baseURL string) []Alternate {
+	pth := site.trimLanguagePrefix(r.URL.Path)
+	df := site.defaultLanguage()
+	alts := make([]Alternate, 0, len(site.Languages)+1)
+	dfURL := ""
+	for _, lang := range site.Languages {
+		p := languagePrefix(lang.Prefix) + pth
+		if !site.hasRoute(p) {
+			continue
+		}
+		u := absURL(baseURL, p)
+		alts = append(alts, Alternate{Lang: lang.Code, URL: u})
+		if lang.Code == df {
+			dfURL = u
+		}
+	}
+	if len(alts) < 2 {
+		return nil
+	}
+	if dfURL != "" {
+		alts = append(alts, Alternate{Lang: hreflangDefault, URL: dfURL})
+	}
+	return alts
+}
+
+// trimLanguagePrefix remove the language prefix from the path if any.
+func (site *Site) trimLanguagePrefix(pth string) string {
+	for _, lang := range site.Languages {
+		prefix := languagePrefix(lang.Prefix)
+		if prefix == "" {
+			continue
+		}
+		if pth == prefix || strings.HasPrefix(pth, prefix+"/") {
+			pth = strings.TrimPrefix(pth, prefix)
+			break
+		}
+	}
+	if pth == "" {
+		return "/"
+	}
+	return pth
+}
+
+// defaultLanguage return code of the default language, default to the first language.
+func (site *Site) defaultLanguage() string {
+	if site.DefaultLanguage != "" {
+		return site.DefaultLanguage
+	}
+	if len(site.Languages) > 0 {
+		return site.Languages[0].Code
+	}
+	return ""
+}
+
+// hasRoute report whether a page is served at the given path.
+func (site *Site) hasRoute(pth string) bool {
+	if site.router == nil {
+		return false
+	}
+	m := mux.RouteMatch{}
+	r := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: pth}}
+	return site.router.Match(r, &m) && m.MatchErr == nil
+}
+
+// languagePrefix normalize the language prefix, i.e: vi/ => /vi.
+func languagePrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+func (site *Site) validateLanguages() error {
+	codes := make(map[string]bool)
+	for _, lang := range site.Languages {
+		if lang.Code == "" {
+			return fmt.Errorf("language: code is required, prefix: %s", lang.Prefix)
+		}
+		if codes[lang.Code] {
+			return fmt.Errorf("language: %s is duplicated", lang.Code)
+		}
+		codes[lang.Code] = true
+	}
+	if site.DefaultLanguage != "" && !codes[site.DefaultLanguage] {
+		return fmt.Errorf("default language: %s not found", site.DefaultLanguage)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package tiny_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHreflang(t *testing.T) {
+	site := newTestSite(t, `
+metadata:
+  base_url: https://example.com
+languages:
+  - code: en
+  - code: vi
+    prefix: /vi
+default_language: en
+pages:
+  about:
+    path: /about
+    components: [about.html]
+  about_vi:
+    path: /vi/about
+    components: [about.html]
+  contact:
+    path: /contact
+    components: [about.html]
+`, map[string]string{
+		"about.html": `[[.Hreflang]]`,
+	})
+	want := `<link rel="alternate" hreflang="en" href="https://example.com/about">
+<link rel="alternate" hreflang="vi" href="https://example.com/vi/about">
+<link rel="alternate" hreflang="x-default" href="https://example.com/about">
+`
+	for _, pth := range []string{"/about", "/vi/about"} {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, pth, nil))
+		if got := rw.Body.String(); got != want {
+			t.Errorf("path: %s, got body=%s, want body=%s", pth, got, want)
+		}
+	}
+	// page without translation has no alternates.
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/contact", nil))
+	if got := rw.Body.String(); got != "" {
+		t.Errorf("got body=%s, want empty body", got)
+	}
+}
+
+func TestHreflangInvalidDefault(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("got no panic, want panic for unknown default language")
+		}
+	}()
+	newTestSite(t, `
+languages:
+  - code: en
+default_language: fr
+pages:
+  about:
+    path: /about
+    components: [about.html]
+`, map[string]string{
+		"about.html": `about`,
+	})
+}
@@ -0,0 +1,30 @@
+package tiny
+
+import (
+	"log"
+)
+
+type (
+	// Logger is the logger used by the site for logging the request handling
+	// and the static generator activities.
+	Logger interface {
+		Debugf(format string, args ...interface{})
+		Infof(format string, args ...interface{})
+		Errorf(format string, args ...interface{})
+	}
+
+	// stdLogger is the default logger which writes logs via the standard log package.
+	stdLogger struct{}
+)
+
+func (stdLogger) Debugf(format string, args ...interface{}) {
+	log.Printf("debug: "+format, args...)
+}
+
+func (stdLogger) Infof(format string, args ...interface{}) {
+	log.Printf("info: "+format, args...)
+}
+
+func (stdLogger) Errorf(format string, args ...interface{}) {
+	log.Printf("error: "+format, args...)
+}
@@ -0,0 +1,55 @@
+package tiny_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+type capturingLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *capturingLogger) log(level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, level+": "+fmt.Sprintf(format, args...))
+}
+
+func (l *capturingLogger) Debugf(format string, args ...interface{}) {
+	l.log("debug", format, args...)
+}
+
+func (l *capturingLogger) Infof(format string, args ...interface{}) {
+	l.log("info", format, args...)
+}
+
+func (l *capturingLogger) Errorf(format string, args ...interface{}) {
+	l.log("error", format, args...)
+}
+
+func TestWithLogger(t *testing.T) {
+	logger := &capturingLogger{}
+	site := newTestSite(t, `
+pages:
+  broken:
+    path: /broken
+    components: [broken.html]
+`, map[string]string{
+		"broken.html": `[[if]]`,
+	}, tiny.WithLogger(logger))
+	serve(site, httptest.NewRequest(http.MethodGet, "/broken", nil))
+	found := false
+	for _, l := range logger.logs {
+		found = found || (strings.HasPrefix(l, "error: parse template: broken") && strings.Contains(l, "missing value for if"))
+	}
+	if !found {
+		t.Errorf("got logs=%v, want template parse error logged", logger.logs)
+	}
+}
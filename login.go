@@ -0,0 +1,110 @@
+package tiny
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrInvalidLogin is the error of the failed logins which the login func doesn't provide an error for.
+	ErrInvalidLogin = NewError(http.StatusUnauthorized, "invalid login")
+)
+
+// loginPage return name of the page served at the login path if any.
+func (site *Site) loginPage() string {
+	for name, p := range site.Pages {
+		if p.Path == site.Login {
+			return name
+		}
+	}
+	return ""
+}
+
+// loginHandler return the handler of the login form submissions.
+// On success, the user is redirected back to the redirect param, default to "/".
+// On failure, the login page is rendered with the error, so that the form can be shown again.
+func (site *Site) loginHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		r = withCSPNonce(withPageDataCache(r))
+		claims, err := site.loginFunc(rw, r)
+		if err == nil && claims == nil {
+			err = ErrInvalidLogin
+		}
+		if err != nil {
+			site.handleLoginError(rw, r, err)
+			return
+		}
+		if site.sessions != nil {
+			if err := site.sessions.Save(rw, claims); err != nil {
+				site.logger.Errorf("save session, err: %v", err)
+				site.handleError(rw, r, site.loginPage(), err)
+				return
+			}
+		}
+		http.Redirect(rw, r, localRedirect(r.FormValue("redirect")), http.StatusSeeOther)
+	})
+}
+
+// handleLoginError render the login page with the error and the status mapped from the error.
+func (site *Site) handleLoginError(rw http.ResponseWriter, r *http.Request, err error) {
+	name := site.loginPage()
+	if name == "" || prefersJSON(r) {
+		site.handleError(rw, r, name, err)
+		return
+	}
+	code := ErrorFromErr(err).Code()
+	if code < 100 || code > 599 {
+		code = http.StatusInternalServerError
+	}
+	rw.Header().Set("Cache-Control", "no-store")
+	site.setPageHeaders(rw, r, name)
+	data := site.getPageData(name, rw, r)
+	data.Error = err
+	if err := site.handlePage(&statusResponseWriter{ResponseWriter: rw, status: code}, r, name, data); err != nil {
+		site.logger.Errorf("serve login page, err: %v", err)
+		site.handleError(rw, r, name, err)
+	}
+}
+
+// localRedirect return the given redirect if it's a local path, otherwise "/",
+// so that the login can't be used to redirect users to other sites.
+// Backslashes and control characters are rejected since browsers strip or treat them as slashes,
+// i.e: /%09/evil.com is followed as //evil.com.
+func localRedirect(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.ContainsRune(p, '\\') {
+		return "/"
+	}
+	for _, c := range p {
+		if c < 0x20 || c == 0x7f {
+			return "/"
+		}
+	}
+	u, err := url.Parse(p)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "/"
+	}
+	return p
+}
+
+// SetLoginHandler set the login func of the site, it's the same as the LoginHandler option
+// but can be called after the site is created, i.e: when the login func depends on the site.
+// The login func is kept for the reloads.
+func (site *Site) SetLoginHandler(f LoginFunc) error {
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	target := site
+	if site.next != nil {
+		// the site was reloaded, the new config serves the requests.
+		target = site.next
+	}
+	if target.Login == "" {
+		return fmt.Errorf("login handler is provided but no login path is configured")
+	}
+	site.loginFunc = f
+	target.loginFunc = f
+	target.setupRouter()
+	site.router, site.handler, site.compress = target.router, target.handler, target.compress
+	return nil
+}
@@ -0,0 +1,112 @@
+package tiny_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestLoginHandler(t *testing.T) {
+	site := newTestSite(t, `
+login: /login
+pages:
+  login:
+    path: /login
+    components: [login.html]
+  admin:
+    path: /admin
+    components: [admin.html]
+    auth: true
+`, map[string]string{
+		"login.html": `[[with .Error]]error: [[.]]|[[end]]redirect: [[.Query.Get "redirect"]]`,
+		"admin.html": `admin`,
+	}, tiny.AuthInfo(func(ctx context.Context) (interface{}, bool) {
+		return nil, false
+	}), tiny.LoginHandler(func(rw http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if r.FormValue("password") != "secret" {
+			return nil, tiny.NewError(http.StatusUnauthorized, "invalid password")
+		}
+		http.SetCookie(rw, &http.Cookie{Name: "session", Value: "jack"})
+		return "jack", nil
+	}))
+	login := func(target, password string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}}
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(site, r)
+	}
+	// the protected page redirects to the login page.
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if got, want := rw.Header().Get("Location"), "/login?redirect=/admin"; got != want {
+		t.Fatalf("got location=%s, want location=%s", got, want)
+	}
+	t.Run("failed", func(t *testing.T) {
+		rw := login("/login?redirect=/admin", "wrong")
+		if rw.Code != http.StatusUnauthorized {
+			t.Errorf("got code=%d, want code=%d", rw.Code, http.StatusUnauthorized)
+		}
+		if got, want := rw.Body.String(), "error: invalid password|redirect: /admin"; got != want {
+			t.Errorf("got body=%s, want body=%s", got, want)
+		}
+		if rw.Header().Get("Set-Cookie") != "" {
+			t.Errorf("got cookie=%s, want no cookie", rw.Header().Get("Set-Cookie"))
+		}
+	})
+	t.Run("success", func(t *testing.T) {
+		rw := login("/login?redirect=/admin", "secret")
+		if rw.Code != http.StatusSeeOther {
+			t.Errorf("got code=%d, want code=%d", rw.Code, http.StatusSeeOther)
+		}
+		if got, want := rw.Header().Get("Location"), "/admin"; got != want {
+			t.Errorf("got location=%s, want location=%s", got, want)
+		}
+		if got := rw.Header().Get("Set-Cookie"); !strings.HasPrefix(got, "session=jack") {
+			t.Errorf("got cookie=%s, want session cookie", got)
+		}
+	})
+	t.Run("external redirect", func(t *testing.T) {
+		for _, redirect := range []string{"//evil.com/phishing", "/%09/evil.com", "/%5Cevil.com", "/%0A/evil.com", "https://evil.com"} {
+			rw := login("/login?redirect="+redirect, "secret")
+			if got, want := rw.Header().Get("Location"), "/"; got != want {
+				t.Errorf("redirect: %s, got location=%s, want location=%s", redirect, got, want)
+			}
+		}
+	})
+	// the login page is still served on GET.
+	rw = serve(site, httptest.NewRequest(http.MethodGet, "/login?redirect=/admin", nil))
+	if got, want := rw.Body.String(), "redirect: /admin"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestSetLoginHandler(t *testing.T) {
+	site := newTestSite(t, `
+login: /login
+pages:
+  login:
+    path: /login
+    components: [login.html]
+`, map[string]string{
+		"login.html": `login`,
+	})
+	post := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/login?redirect=/home", nil)
+		return serve(site, r)
+	}
+	if rw := post(); rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got code=%d, want code=%d before the login handler is set", rw.Code, http.StatusMethodNotAllowed)
+	}
+	if err := site.SetLoginHandler(func(rw http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return "jack", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if rw := post(); rw.Code != http.StatusSeeOther || rw.Header().Get("Location") != "/home" {
+		t.Errorf("got code=%d, location=%s, want code=%d, location=/home", rw.Code, rw.Header().Get("Location"), http.StatusSeeOther)
+	}
+}
@@ -1,38 +1,405 @@
 package tiny
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 const (
-	defaultMaxAge = 30 * 24 * time.Hour
+	defaultMaxAge     = 30 * 24 * time.Hour
+	defaultRetryAfter = time.Hour
+	// limiters which are not used in this duration are removed.
+	rateLimitIdleTimeout = 10 * time.Minute
+
+	// AuthModeAuto redirects the browser navigations to the login page,
+	// and responds 401 to the API/AJAX requests, i.e: requests prefer JSON or have a X-Requested-With header.
+	AuthModeAuto = ""
+	// AuthModeRedirect always redirects the unauthenticated requests to the login page.
+	AuthModeRedirect = "redirect"
+	// AuthModeStatus always responds 401 to the unauthenticated requests.
+	AuthModeStatus = "status"
 )
 
 // Cache cache static resources.
+// A strong ETag is computed from the body of successful GET responses, or from the modification time
+// and size of the files, i.e: served by http.FileServer, which are written as is instead of being buffered.
+// 304 Not Modified is returned if the If-None-Match header of the request matches it.
+// The ETag of the HEAD responses is available only for the files, since their bodies are empty.
 func Cache(maxAge time.Duration) func(http.Handler) http.Handler {
 	if maxAge == 0 {
 		maxAge = defaultMaxAge
 	}
+	cacheControl := fmt.Sprintf("public, max-age=%d", int64(maxAge.Seconds()))
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(
 			func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(maxAge.Seconds())))
-				h.ServeHTTP(w, r)
+				cw := &cacheResponseWriter{
+					ResponseWriter: w,
+					cacheControl:   cacheControl,
+					etag:           r.Method == http.MethodGet || r.Method == http.MethodHead,
+					head:           r.Method == http.MethodHead,
+					ifNoneMatch:    r.Header.Get("If-None-Match"),
+				}
+				h.ServeHTTP(cw, r)
+				cw.finish()
 			})
 	}
 }
 
+// cacheResponseWriter sets the Cache-Control header once the response status is known.
+// Only 2xx and 3xx responses are cached, errors are marked as no-store.
+// The body of 200 responses is buffered for computing the ETag, unless it's flushed or it's a file.
+type cacheResponseWriter struct {
+	http.ResponseWriter
+	cacheControl string
+	etag         bool
+	head         bool
+	ifNoneMatch  string
+	status       int
+	buf          bytes.Buffer
+	passthrough  bool
+	// discard is set if 304 Not Modified is written for a file, its body is discarded.
+	discard bool
+}
+
+func (w *cacheResponseWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	if code >= 200 && code < 400 {
+		w.Header().Set("Cache-Control", w.cacheControl)
+	} else {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	if code == http.StatusOK && w.etag && w.Header().Get("Last-Modified") != "" {
+		w.writeFileHeader()
+		return
+	}
+	if code != http.StatusOK || !w.etag || w.head {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// writeFileHeader write the header of a file with the ETag computed from its modification time and size.
+func (w *cacheResponseWriter) writeFileHeader() {
+	w.passthrough = true
+	h := w.Header()
+	if h.Get("ETag") == "" {
+		mod, err := http.ParseTime(h.Get("Last-Modified"))
+		size, serr := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+		if err == nil && serr == nil {
+			h.Set("ETag", fmt.Sprintf(`"%x-%x"`, mod.Unix(), size))
+		}
+	}
+	if etag := h.Get("ETag"); etag != "" && etagMatch(w.ifNoneMatch, etag) {
+		w.discard = true
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		h.Del("Content-Encoding")
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.ResponseWriter.WriteHeader(http.StatusOK)
+}
+
+func (w *cacheResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(b), nil
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// Flush write the buffered response, the ETag is not computed for flushed responses.
+func (w *cacheResponseWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.passthrough {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish set the ETag and write the buffered response,
+// or 304 Not Modified if the ETag matches the If-None-Match header of the request.
+func (w *cacheResponseWriter) finish() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return
+	}
+	h := w.Header()
+	etag := h.Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(w.buf.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:]) + `"`
+		h.Set("ETag", etag)
+	}
+	if etagMatch(w.ifNoneMatch, etag) {
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		h.Del("Content-Encoding")
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.buf.Bytes())
+}
+
+// etagMatch report whether the If-None-Match header matches the given ETag,
+// using the weak comparison as specified for If-None-Match.
+func etagMatch(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NoStore provides middleware for disabling caching of all responses, i.e: in development.
+// The Cache-Control headers set by the handlers are overridden.
+func NoStore() func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(&noStoreResponseWriter{ResponseWriter: rw}, r)
+		})
+	}
+}
+
+// noStoreResponseWriter sets Cache-Control: no-store right before the header is written.
+type noStoreResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *noStoreResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Del("Expires")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *noStoreResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *noStoreResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // AuthRequired provides middleware for redirecting user to login page if they have not logged in yet.
-func AuthRequired(loginPath string, authInfoFunc AuthInfoFunc) func(http.Handler) http.Handler {
+// The optional mode controls how the unauthenticated requests are rejected, default to AuthModeAuto.
+// 401 is always responded if the login path is empty.
+func AuthRequired(loginPath string, authInfoFunc AuthInfoFunc, mode ...string) func(http.Handler) http.Handler {
+	m := AuthModeAuto
+	if len(mode) > 0 {
+		m = mode[0]
+	}
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-			if _, ok := authInfoFunc(r.Context()); !ok {
-				http.Redirect(rw, r, fmt.Sprintf("%s?redirect=%s", loginPath, r.URL.Path), http.StatusFound)
+			if _, ok := authInfoFunc(r.Context()); ok {
+				h.ServeHTTP(rw, r)
+				return
+			}
+			if loginPath == "" || m == AuthModeStatus || (m == AuthModeAuto && isAPIRequest(r)) {
+				statusError(rw, r, http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(rw, r, fmt.Sprintf("%s?redirect=%s", loginPath, r.URL.Path), http.StatusFound)
+		})
+	}
+}
+
+// isAPIRequest report whether the request is an API/AJAX request rather than a browser navigation.
+func isAPIRequest(r *http.Request) bool {
+	return prefersJSON(r) || r.Header.Get("X-Requested-With") != ""
+}
+
+// statusError write the status as JSON if the request prefers JSON, otherwise as plain text.
+func statusError(rw http.ResponseWriter, r *http.Request, code int) {
+	msg := http.StatusText(code)
+	if !prefersJSON(r) {
+		http.Error(rw, msg, code)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(code)
+	fmt.Fprintf(rw, "{\"code\":%d,\"error\":%q}\n", code, msg)
+}
+
+// MaintenanceMode provides middleware for serving the given page with status 503 to all requests
+// while enabled returns true. Requests to the allowed paths (prefix) or from the allowed IPs pass through.
+func MaintenanceMode(enabled func() bool, page http.Handler, allows ...string) func(http.Handler) http.Handler {
+	return maintenanceMode(enabled, page, defaultRetryAfter, allows...)
+}
+
+// maintenanceMode is MaintenanceMode with the given Retry-After duration.
+func maintenanceMode(enabled func() bool, page http.Handler, retryAfter time.Duration, allows ...string) func(http.Handler) http.Handler {
+	if retryAfter <= 0 {
+		retryAfter = defaultRetryAfter
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if !enabled() || isAllowed(r, allows) {
+				h.ServeHTTP(rw, r)
+				return
+			}
+			rw.Header().Set("Retry-After", strconv.FormatInt(int64(retryAfter.Seconds()), 10))
+			// the page writes its own headers, the status is forced to 503 when they are written.
+			sw := &statusResponseWriter{ResponseWriter: rw, status: http.StatusServiceUnavailable, force: true}
+			page.ServeHTTP(sw, r)
+			if !sw.wroteHeader {
+				sw.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+// isAllowed report whether the request path or client IP matches one of the allows.
+// An allow which starts with "/" is treated as a path prefix, otherwise an IP.
+func isAllowed(r *http.Request, allows []string) bool {
+	ip := clientIP(r)
+	for _, allow := range allows {
+		if strings.HasPrefix(allow, "/") {
+			if strings.HasPrefix(r.URL.Path, allow) {
+				return true
+			}
+			continue
+		}
+		if allow == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimit provides middleware for limiting number of requests per second of each client IP.
+// Requests exceed the limit are rejected with status 429.
+func RateLimit(rps float64, burst int) func(http.Handler) http.Handler {
+	type client struct {
+		limiter  *rate.Limiter
+		lastSeen time.Time
+	}
+	var (
+		mu          sync.Mutex
+		clients     = make(map[string]*client)
+		lastCleanup = time.Now()
+	)
+	allow := func(ip string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if now.Sub(lastCleanup) > rateLimitIdleTimeout {
+			for k, c := range clients {
+				if now.Sub(c.lastSeen) > rateLimitIdleTimeout {
+					delete(clients, k)
+				}
+			}
+			lastCleanup = now
+		}
+		c, ok := clients[ip]
+		if !ok {
+			c = &client{limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+			clients[ip] = c
+		}
+		c.lastSeen = now
+		return c.limiter.Allow()
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if !allow(clientIP(r)) {
+				http.Error(rw, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
 				return
 			}
 			h.ServeHTTP(rw, r)
 		})
 	}
 }
+
+// CanonicalHostRedirect provides middleware for redirecting requests on other hosts or schemes
+// to the given canonical host and scheme with status 301, path and query are preserved.
+// Scheme of requests behind proxies is detected via the X-Forwarded-Proto header.
+func CanonicalHostRedirect(scheme, host string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Host, host) && requestScheme(r) == scheme {
+				h.ServeHTTP(rw, r)
+				return
+			}
+			u := *r.URL
+			u.Scheme = scheme
+			u.Host = host
+			http.Redirect(rw, r, u.String(), http.StatusMovedPermanently)
+		})
+	}
+}
+
+// requestScheme return scheme of the request, X-Forwarded-Proto header takes priority.
+func requestScheme(r *http.Request) string {
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		return strings.ToLower(strings.TrimSpace(strings.Split(proto, ",")[0]))
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// requestBaseURL return base URL of the request, i.e: https://example.com.
+// X-Forwarded-Host and X-Forwarded-Proto headers take priority.
+func requestBaseURL(r *http.Request) string {
+	host := r.Host
+	if fh := r.Header.Get("X-Forwarded-Host"); fh != "" {
+		host = strings.TrimSpace(strings.Split(fh, ",")[0])
+	}
+	return requestScheme(r) + "://" + host
+}
+
+// clientIP return IP of the client sent the request.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
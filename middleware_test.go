@@ -0,0 +1,506 @@
+package tiny_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestMaintenance(t *testing.T) {
+	enabled := true
+	site := newTestSite(t, `
+maintenance_retry_after: 2m
+pages:
+  index:
+    path: /
+    components: [index.html]
+  health:
+    path: /health
+    components: [health.html]
+  maintenance:
+    path: /maintenance
+    components: [maintenance.html]
+    max_age: 1m
+`, map[string]string{
+		"index.html":       `index`,
+		"health.html":      `ok`,
+		"maintenance.html": `under maintenance`,
+	}, tiny.Maintenance(func() bool { return enabled }, "maintenance", "/health", "10.0.0.1"))
+
+	cases := []struct {
+		name       string
+		enabled    bool
+		path       string
+		remoteAddr string
+		code       int
+		body       string
+	}{
+		{
+			name:    "maintenance on",
+			enabled: true,
+			path:    "/",
+			code:    http.StatusServiceUnavailable,
+			body:    "under maintenance",
+		},
+		{
+			name:    "maintenance on, allowed path",
+			enabled: true,
+			path:    "/health",
+			code:    http.StatusOK,
+			body:    "ok",
+		},
+		{
+			name:       "maintenance on, allowed ip",
+			enabled:    true,
+			path:       "/",
+			remoteAddr: "10.0.0.1:1234",
+			code:       http.StatusOK,
+			body:       "index",
+		},
+		{
+			name:    "maintenance off",
+			enabled: false,
+			path:    "/",
+			code:    http.StatusOK,
+			body:    "index",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			enabled = c.enabled
+			r := httptest.NewRequest(http.MethodGet, c.path, nil)
+			if c.remoteAddr != "" {
+				r.RemoteAddr = c.remoteAddr
+			}
+			rw := serve(site, r)
+			if rw.Code != c.code {
+				t.Errorf("got code=%d, want code=%d", rw.Code, c.code)
+			}
+			if !strings.Contains(rw.Body.String(), c.body) {
+				t.Errorf("got body=%s, want body=%s", rw.Body.String(), c.body)
+			}
+			// the headers of the maintenance page are sent along with the status.
+			if h := rw.Result().Header; c.code == http.StatusServiceUnavailable && (h.Get("Retry-After") != "120" || h.Get("Cache-Control") != "public, max-age=60") {
+				t.Errorf("got Retry-After=%s, Cache-Control=%s, want Retry-After=120, Cache-Control=public, max-age=60", h.Get("Retry-After"), h.Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestMaintenanceUnknownPage(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("got no panic, want panic for unknown maintenance page")
+		}
+	}()
+	newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+`, map[string]string{
+		"index.html": `index`,
+	}, tiny.Maintenance(func() bool { return true }, "maintenace"))
+}
+
+func TestPageRateLimit(t *testing.T) {
+	config := `
+pages:
+  index:
+    path: /
+    components: [index.html]
+  contact:
+    path: /contact
+    components: [contact.html]
+    rate_limit:
+      rps: 0.001
+      burst: %d
+`
+	files := map[string]string{
+		"index.html":   `index`,
+		"contact.html": `contact`,
+	}
+	site := newTestSite(t, fmt.Sprintf(config, 2), files)
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, "/contact", nil))
+		if rw.Code != want {
+			t.Errorf("contact request: %d, got code=%d, want code=%d", i, rw.Code, want)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rw.Code != http.StatusOK {
+			t.Errorf("index request: %d, got code=%d, want code=%d", i, rw.Code, http.StatusOK)
+		}
+	}
+	t.Run("invalid rate limit", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("got no panic, want panic for invalid rate limit")
+			}
+		}()
+		newTestSite(t, fmt.Sprintf(config, 0), files)
+	})
+}
+
+func TestCanonicalHost(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+  blog:
+    path: /blog
+    components: [index.html]
+`, map[string]string{
+		"index.html": `index`,
+	}, tiny.CanonicalHost("https", "example.com"))
+	cases := []struct {
+		name     string
+		url      string
+		proto    string
+		code     int
+		location string
+	}{
+		{
+			name:     "www to non-www",
+			url:      "https://www.example.com/blog?page=2",
+			code:     http.StatusMovedPermanently,
+			location: "https://example.com/blog?page=2",
+		},
+		{
+			name:     "http to https",
+			url:      "http://example.com/blog",
+			code:     http.StatusMovedPermanently,
+			location: "https://example.com/blog",
+		},
+		{
+			name:     "http to https behind proxy",
+			url:      "http://example.com/blog",
+			proto:    "http",
+			code:     http.StatusMovedPermanently,
+			location: "https://example.com/blog",
+		},
+		{
+			name:  "already canonical behind proxy",
+			url:   "http://example.com/blog",
+			proto: "https",
+			code:  http.StatusOK,
+		},
+		{
+			name: "already canonical",
+			url:  "https://example.com/",
+			code: http.StatusOK,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, c.url, nil)
+			if c.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", c.proto)
+			}
+			rw := serve(site, r)
+			if rw.Code != c.code {
+				t.Errorf("got code=%d, want code=%d", rw.Code, c.code)
+			}
+			if got := rw.Header().Get("Location"); got != c.location {
+				t.Errorf("got location=%s, want location=%s", got, c.location)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	h := tiny.Cache(time.Minute)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			rw.Write([]byte("ok"))
+		case "/moved":
+			http.Redirect(rw, r, "/ok", http.StatusMovedPermanently)
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+	cases := []struct {
+		path         string
+		cacheControl string
+	}{
+		{path: "/ok", cacheControl: "public, max-age=60"},
+		{path: "/moved", cacheControl: "public, max-age=60"},
+		{path: "/not-found", cacheControl: "no-store"},
+	}
+	for _, c := range cases {
+		rw := serve(h, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if got := rw.Header().Get("Cache-Control"); got != c.cacheControl {
+			t.Errorf("path: %s, got Cache-Control=%s, want Cache-Control=%s", c.path, got, c.cacheControl)
+		}
+	}
+}
+
+type userKey struct{}
+
+func TestAuthRequired(t *testing.T) {
+	authInfo := func(ctx context.Context) (interface{}, bool) {
+		user, ok := ctx.Value(userKey{}).(string)
+		return user, ok
+	}
+	h := tiny.AuthRequired("/login", authInfo)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("secret"))
+	}))
+	// authenticated request must reach the wrapped handler.
+	r := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userKey{}, "jack"))
+	rw := serve(h, r)
+	if rw.Code != http.StatusOK || rw.Body.String() != "secret" {
+		t.Errorf("got code=%d, body=%s, want code=%d, body=secret", rw.Code, rw.Body.String(), http.StatusOK)
+	}
+	// unauthenticated request is redirected to the login page.
+	rw = serve(h, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if got, want := rw.Header().Get("Location"), "/login?redirect=/admin"; rw.Code != http.StatusFound || got != want {
+		t.Errorf("got code=%d, location=%s, want code=%d, location=%s", rw.Code, got, http.StatusFound, want)
+	}
+}
+
+func TestAuthMode(t *testing.T) {
+	site := newTestSite(t, `
+login: /login
+pages:
+  login:
+    path: /login
+    components: [login.html]
+  admin:
+    path: /admin
+    components: [admin.html]
+    auth: true
+  api:
+    path: /api/posts
+    components: [admin.html]
+    auth: true
+    auth_mode: status
+  legacy:
+    path: /legacy
+    components: [admin.html]
+    auth: true
+    auth_mode: redirect
+`, map[string]string{
+		"login.html": `login`,
+		"admin.html": `admin`,
+	}, tiny.AuthInfo(func(ctx context.Context) (interface{}, bool) {
+		return nil, false
+	}))
+	cases := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		code    int
+	}{
+		{name: "browser navigation", path: "/admin", headers: map[string]string{"Accept": "text/html,application/xhtml+xml,*/*;q=0.8"}, code: http.StatusFound},
+		{name: "json accept", path: "/admin", headers: map[string]string{"Accept": "application/json"}, code: http.StatusUnauthorized},
+		{name: "ajax", path: "/admin", headers: map[string]string{"X-Requested-With": "XMLHttpRequest"}, code: http.StatusUnauthorized},
+		{name: "status mode", path: "/api/posts", code: http.StatusUnauthorized},
+		{name: "redirect mode", path: "/legacy", headers: map[string]string{"Accept": "application/json"}, code: http.StatusFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, c.path, nil)
+			for k, v := range c.headers {
+				r.Header.Set(k, v)
+			}
+			rw := serve(site, r)
+			if rw.Code != c.code {
+				t.Errorf("got code=%d, want code=%d", rw.Code, c.code)
+			}
+			if c.code == http.StatusFound && rw.Header().Get("Location") != "/login?redirect="+c.path {
+				t.Errorf("got location=%s, want redirect to login", rw.Header().Get("Location"))
+			}
+			if c.code == http.StatusUnauthorized && rw.Header().Get("Location") != "" {
+				t.Errorf("got location=%s, want no redirect", rw.Header().Get("Location"))
+			}
+		})
+	}
+	r := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	r.Header.Set("Accept", "application/json")
+	rw := serve(site, r)
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &body); err != nil || body["code"] != float64(http.StatusUnauthorized) {
+		t.Errorf("got body=%s, err=%v, want JSON error with code 401", rw.Body.String(), err)
+	}
+}
+
+func TestInvalidAuthMode(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("got no panic, want panic for unknown auth mode")
+		}
+	}()
+	newTestSite(t, `
+pages:
+  admin:
+    path: /admin
+    components: [admin.html]
+    auth: true
+    auth_mode: deny
+`, map[string]string{
+		"admin.html": `admin`,
+	}, tiny.AuthInfo(func(ctx context.Context) (interface{}, bool) {
+		return nil, false
+	}))
+}
+
+func TestCacheETag(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  static:
+    path: /static/
+    data: file://static/
+`, map[string]string{
+		"static/app.css": `body { color: red; }`,
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/static/app.css", nil))
+	etag := rw.Header().Get("ETag")
+	if rw.Code != http.StatusOK || etag == "" {
+		t.Fatalf("got code=%d, ETag=%s, want code=%d and an ETag", rw.Code, etag, http.StatusOK)
+	}
+	if got := rw.Body.String(); got != `body { color: red; }` {
+		t.Errorf("got body=%s, want the file content", got)
+	}
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag} {
+		r := httptest.NewRequest(http.MethodGet, "/static/app.css", nil)
+		r.Header.Set("If-None-Match", inm)
+		rw = serve(site, r)
+		if rw.Code != http.StatusNotModified || rw.Body.Len() != 0 {
+			t.Errorf("If-None-Match: %s, got code=%d, body=%s, want code=%d with empty body", inm, rw.Code, rw.Body.String(), http.StatusNotModified)
+		}
+		if got := rw.Header().Get("ETag"); got != etag {
+			t.Errorf("got ETag=%s, want ETag=%s", got, etag)
+		}
+	}
+	r := httptest.NewRequest(http.MethodGet, "/static/app.css", nil)
+	r.Header.Set("If-None-Match", `"stale"`)
+	if rw = serve(site, r); rw.Code != http.StatusOK {
+		t.Errorf("got code=%d, want code=%d for stale ETag", rw.Code, http.StatusOK)
+	}
+	// HEAD requests of the files have the same ETag as the GET requests.
+	h := tiny.Cache(time.Minute)(http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	rw = serve(h, httptest.NewRequest(http.MethodHead, "/static/app.css", nil))
+	if got := rw.Header().Get("ETag"); got != etag {
+		t.Errorf("got HEAD ETag=%s, want ETag=%s", got, etag)
+	}
+	r = httptest.NewRequest(http.MethodHead, "/static/app.css", nil)
+	r.Header.Set("If-None-Match", etag)
+	if rw = serve(h, r); rw.Code != http.StatusNotModified {
+		t.Errorf("got HEAD code=%d, want code=%d", rw.Code, http.StatusNotModified)
+	}
+}
+
+func TestCacheETagHead(t *testing.T) {
+	h := tiny.Cache(time.Minute)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			rw.Write([]byte("ok"))
+		}
+	}))
+	rw := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rw.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("got no ETag, want ETag of the GET response")
+	}
+	r := httptest.NewRequest(http.MethodHead, "/", nil)
+	r.Header.Set("If-None-Match", etag)
+	rw = serve(h, r)
+	if got := rw.Header().Get("ETag"); rw.Code != http.StatusOK || got != "" {
+		t.Errorf("got code=%d, ETag=%s, want code=%d without ETag of the empty body", rw.Code, got, http.StatusOK)
+	}
+}
+
+func TestStaticAndPageMaxAge(t *testing.T) {
+	site := newTestSite(t, `
+static_max_age: 8760h
+page_max_age: 5m
+pages:
+  index:
+    path: /
+    components: [index.html]
+  about:
+    path: /about
+    components: [index.html]
+    max_age: 1h
+  private:
+    path: /private
+    components: [index.html]
+    auth: true
+  static:
+    path: /static/
+    data: file://static/
+  favicon:
+    path: /favicon.ico
+    data: file://favicon.ico
+  logo:
+    path: /logo.png
+    data: file://logo.png
+    max_age: 2h
+`, map[string]string{
+		"index.html":    `index`,
+		"static/app.js": `console.log("hello")`,
+		"favicon.ico":   `icon`,
+		"logo.png":      `logo`,
+	}, tiny.AuthInfo(func(ctx context.Context) (interface{}, bool) {
+		return "user", true
+	}))
+	cases := []struct {
+		path         string
+		cacheControl string
+	}{
+		{path: "/", cacheControl: "public, max-age=300"},
+		{path: "/about", cacheControl: "public, max-age=3600"},
+		{path: "/private", cacheControl: "private, max-age=300"},
+		{path: "/static/app.js", cacheControl: "public, max-age=31536000"},
+		{path: "/favicon.ico", cacheControl: "public, max-age=31536000"},
+		{path: "/logo.png", cacheControl: "public, max-age=7200"},
+		{path: "/not-found", cacheControl: ""},
+	}
+	for _, c := range cases {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if got := rw.Header().Get("Cache-Control"); got != c.cacheControl {
+			t.Errorf("path=%s, got Cache-Control=%s, want Cache-Control=%s", c.path, got, c.cacheControl)
+		}
+	}
+}
+
+func TestNoStoreOnReload(t *testing.T) {
+	config := `
+reload: %v
+page_max_age: 5m
+pages:
+  index:
+    path: /
+    components: [index.html]
+  static:
+    path: /static/
+    data: file://static/
+`
+	for _, reload := range []bool{true, false} {
+		site := newTestSite(t, fmt.Sprintf(config, reload), map[string]string{
+			"index.html":    `index`,
+			"static/app.js": `console.log("hello")`,
+		})
+		for path, cacheControl := range map[string]string{"/": "public, max-age=300", "/static/app.js": "public, max-age=2592000"} {
+			if reload {
+				cacheControl = "no-store"
+			}
+			rw := serve(site, httptest.NewRequest(http.MethodGet, path, nil))
+			if rw.Code != http.StatusOK {
+				t.Errorf("reload=%v, path=%s, got code=%d, want code=%d", reload, path, rw.Code, http.StatusOK)
+			}
+			if got := rw.Header().Get("Cache-Control"); got != cacheControl {
+				t.Errorf("reload=%v, path=%s, got Cache-Control=%s, want Cache-Control=%s", reload, path, got, cacheControl)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package tiny
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// prefersJSON report whether the client prefers a JSON response than a HTML one,
+// base on the Accept header of the request.
+func prefersJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+	jsonQ, htmlQ := -1.0, -1.0
+	for _, v := range strings.Split(accept, ",") {
+		mediaType, q, ok := parseQValue(v)
+		if !ok {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		case "text/html", "*/*":
+			if q > htmlQ {
+				htmlQ = q
+			}
+		}
+	}
+	return jsonQ > 0 && jsonQ > htmlQ
+}
+
+// acceptsHTML report whether the client accepts HTML explicitly, i.e: browser navigations.
+func acceptsHTML(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, q, ok := parseQValue(v)
+		if ok && mediaType == "text/html" && q > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding report whether the client accepts the given content encoding,
+// base on the Accept-Encoding header of the request.
+// An explicit entry of the encoding takes precedence over the wildcard, i.e: gzip;q=0, * refuses gzip.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	wildcard := false
+	for _, v := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		enc, q, ok := parseQValue(v)
+		if !ok {
+			continue
+		}
+		if enc == encoding {
+			return q > 0
+		}
+		if enc == "*" {
+			wildcard = q > 0
+		}
+	}
+	return wildcard
+}
+
+// parseQValue parse an element of the Accept like headers, i.e: text/html;q=0.9.
+func parseQValue(v string) (value string, q float64, ok bool) {
+	value, params, err := mime.ParseMediaType(strings.TrimSpace(v))
+	if err != nil {
+		return "", 0, false
+	}
+	q = 1.0
+	if v, ok := params["q"]; ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			q = f
+		}
+	}
+	return value, q, true
+}
+
+// writeJSON write the data as JSON, the data is encoded before written so that errors can still be handled.
+func writeJSON(rw http.ResponseWriter, data interface{}) error {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(data); err != nil {
+		return err
+	}
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, err := rw.Write(buf.Bytes())
+	return err
+}
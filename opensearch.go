@@ -0,0 +1,112 @@
+package tiny
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	openSearchNS          = "http://a9.com/-/spec/opensearch/1.1/"
+	openSearchContentType = "application/opensearchdescription+xml"
+	openSearchMaxShortLen = 16
+)
+
+type (
+	// OpenSearch is an OpenSearch description document,
+	// which allows browsers to integrate with the site search.
+	OpenSearch struct {
+		XMLName       xml.Name         `xml:"OpenSearchDescription"`
+		XMLNS         string           `xml:"xmlns,attr"`
+		ShortName     string           `xml:"ShortName"`
+		LongName      string           `xml:"LongName,omitempty"`
+		Description   string           `xml:"Description"`
+		InputEncoding string           `xml:"InputEncoding,omitempty"`
+		Image         *OpenSearchImage `xml:"Image,omitempty"`
+		URLs          []OpenSearchURL  `xml:"Url"`
+	}
+
+	OpenSearchImage struct {
+		Width  int    `xml:"width,attr,omitempty"`
+		Height int    `xml:"height,attr,omitempty"`
+		Type   string `xml:"type,attr,omitempty"`
+		URL    string `xml:",chardata"`
+	}
+
+	// OpenSearchURL describes a search URL, Template must contain the {searchTerms} placeholder.
+	OpenSearchURL struct {
+		Type     string `xml:"type,attr"`
+		Method   string `xml:"method,attr,omitempty"`
+		Template string `xml:"template,attr"`
+	}
+
+	OpenSearchDataHandler = func(rw http.ResponseWriter, r *http.Request) OpenSearch
+)
+
+// Marshal return the XML document of the OpenSearch description.
+func (o OpenSearch) Marshal() ([]byte, error) {
+	if o.XMLNS == "" {
+		o.XMLNS = openSearchNS
+	}
+	b, err := xml.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+func (site *Site) SetOpenSearchDataHandler(name string, h OpenSearchDataHandler) error {
+	return site.SetDataHandler(name, func(rw http.ResponseWriter, r *http.Request) interface{} {
+		return h(rw, r)
+	})
+}
+
+// openSearchDataHandler return a DataHandler which derives the OpenSearch description from the page metadata.
+// The page data is used as the search URL, relative URL is prefixed with the base_url.
+func (site *Site) openSearchDataHandler(name string, searchURL string) DataHandler {
+	return func(rw http.ResponseWriter, r *http.Request) interface{} {
+		md := site.getPageMetaData(name)
+		u := searchURL
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			u = md.BaseURL() + u
+		}
+		shortName := []rune(md.SiteName())
+		if len(shortName) > openSearchMaxShortLen {
+			shortName = shortName[:openSearchMaxShortLen]
+		}
+		o := OpenSearch{
+			ShortName:     string(shortName),
+			LongName:      md.SiteName(),
+			Description:   md.Description(),
+			InputEncoding: "UTF-8",
+			URLs: []OpenSearchURL{
+				{
+					Type:     "text/html",
+					Template: u,
+				},
+			},
+		}
+		if img := md.Image(); img != "" {
+			o.Image = &OpenSearchImage{URL: img}
+		}
+		return o
+	}
+}
+
+func renderOpenSearch(rw http.ResponseWriter, data interface{}) error {
+	o, ok := data.(OpenSearch)
+	if !ok {
+		return NewError(http.StatusInternalServerError, "invalid opensearch data: %T", data)
+	}
+	if len(o.URLs) == 0 || !strings.Contains(o.URLs[0].Template, "{searchTerms}") {
+		return NewError(http.StatusInternalServerError, "invalid opensearch url template, missing {searchTerms}")
+	}
+	b, err := o.Marshal()
+	if err != nil {
+		return fmt.Errorf("marshal opensearch, err: %w", err)
+	}
+	rw.Header().Set("Content-Type", openSearchContentType)
+	_, err = rw.Write(b)
+	return err
+}
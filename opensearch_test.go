@@ -0,0 +1,44 @@
+package tiny_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestOpenSearch(t *testing.T) {
+	site := newTestSite(t, `
+metadata:
+  site_name: Tiny Blog
+  description: Ideas worth sharing
+  base_url: https://example.com
+pages:
+  opensearch:
+    path: /opensearch.xml
+    data_type: opensearch
+    data: /search?q={searchTerms}
+`, map[string]string{})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/opensearch.xml", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got code=%d, want code=%d", rw.Code, http.StatusOK)
+	}
+	if got, want := rw.Header().Get("Content-Type"), "application/opensearchdescription+xml"; got != want {
+		t.Errorf("got Content-Type=%s, want Content-Type=%s", got, want)
+	}
+	o := tiny.OpenSearch{}
+	if err := xml.Unmarshal(rw.Body.Bytes(), &o); err != nil {
+		t.Fatal(err)
+	}
+	if o.XMLNS != "http://a9.com/-/spec/opensearch/1.1/" {
+		t.Errorf("got xmlns=%s, want opensearch namespace", o.XMLNS)
+	}
+	if o.ShortName != "Tiny Blog" || o.Description != "Ideas worth sharing" {
+		t.Errorf("got ShortName=%s, Description=%s, want values from metadata", o.ShortName, o.Description)
+	}
+	if len(o.URLs) != 1 || o.URLs[0].Template != "https://example.com/search?q={searchTerms}" {
+		t.Errorf("got urls=%v, want url template with {searchTerms} placeholder", o.URLs)
+	}
+}
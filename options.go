@@ -1,5 +1,11 @@
 package tiny
 
+import (
+	"io"
+	"io/fs"
+	"time"
+)
+
 type (
 	Option func(site *Site)
 )
@@ -23,3 +29,198 @@ func AuthInfo(f AuthInfoFunc) Option {
 		site.authInfo = f
 	}
 }
+
+// LoginHandler handles the POST requests to the login page using the given func.
+// On success, the claims are saved to the session store if any, and the user is redirected back
+// to the redirect param of the request, i.e: /login?redirect=/admin.
+// On failure, the login page is rendered with the error via `.Error` of PageData.
+// The login func can also be set after the site is created via Site.SetLoginHandler.
+func LoginHandler(f LoginFunc) Option {
+	return func(site *Site) {
+		site.loginFunc = f
+	}
+}
+
+// WithSessionStore loads the session claims of the requests from the given store, see Sessions.
+// The claims are used as the auth info if no AuthInfo is provided, and the claims of the successful logins
+// are saved to the store, see LoginHandler.
+func WithSessionStore(store SessionStore) Option {
+	return func(site *Site) {
+		site.sessions = store
+	}
+}
+
+// CSRFProtection enables the CSRF protection of the unsafe requests, the same as `csrf: true` in the config.
+// See CSRF middleware.
+func CSRFProtection() Option {
+	return func(site *Site) {
+		site.CSRF = true
+	}
+}
+
+// Maintenance serves the given page with status 503 to all requests while enabled returns true.
+// The allows can be path prefixes (start with "/") or client IPs that bypass the maintenance page.
+// Since enabled is evaluated per request, maintenance mode can be switched without restart.
+// The Retry-After header is set to the maintenance_retry_after of the config, default to 1 hour.
+func Maintenance(enabled func() bool, page string, allows ...string) Option {
+	return func(site *Site) {
+		site.maintenance = page
+		site.middlewares = append(site.middlewares, maintenanceMode(enabled, site.getPageHandler(page), site.MaintenanceRetryAfter, allows...))
+	}
+}
+
+// ResponseTransform registers funcs for modifying the rendered content of all HTML pages
+// before it is written to the client, i.e: append analytics snippets or a build-version comment.
+// The funcs are applied in order.
+func ResponseTransform(funcs ...ResponseTransformFunc) Option {
+	return func(site *Site) {
+		site.transforms = append(site.transforms, funcs...)
+	}
+}
+
+// Home serves the given page at "/" in addition to its own path.
+// The page must exist in the site config.
+func Home(page string) Option {
+	return func(site *Site) {
+		site.Home = page
+	}
+}
+
+// Flags provides feature flags for the templates via `.Flags` of PageData and the `flag` func.
+// The flags are read per request, hence they can be switched at runtime.
+func Flags(f FlagsFunc) Option {
+	return func(site *Site) {
+		site.flags = f
+	}
+}
+
+// BuildInfo provides the build information of the site, which is usually set via ldflags.
+// The information can be accessed in templates via `.BuildInfo` of PageData or the `build_info` func.
+func BuildInfo(version, commit, buildTime string) Option {
+	return func(site *Site) {
+		site.buildInfo = Build{
+			Version:   version,
+			Commit:    commit,
+			BuildTime: buildTime,
+		}
+	}
+}
+
+// IncludeTags only registers the pages which have one of the given tags.
+// This allows a config to power several sites.
+func IncludeTags(tags ...string) Option {
+	return func(site *Site) {
+		site.includeTags = append(site.includeTags, tags...)
+	}
+}
+
+// ExcludeTags doesn't register the pages which have one of the given tags.
+func ExcludeTags(tags ...string) Option {
+	return func(site *Site) {
+		site.excludeTags = append(site.excludeTags, tags...)
+	}
+}
+
+// CanonicalHost redirects requests on other hosts or schemes to the given canonical host and scheme,
+// i.e: CanonicalHost("https", "example.com") redirects http://www.example.com/x to https://example.com/x.
+func CanonicalHost(scheme, host string) Option {
+	return func(site *Site) {
+		site.middlewares = append(site.middlewares, CanonicalHostRedirect(scheme, host))
+	}
+}
+
+// DetectBaseURL derives the base_url from the Host, X-Forwarded-Host and X-Forwarded-Proto headers
+// of each request if it's not configured, so that canonical URLs match the actual host
+// when the site is deployed to unpredictable hosts, i.e: preview URLs.
+// Only enable it when the site is behind a proxy which sets/overrides those headers,
+// since they can be spoofed by clients.
+func DetectBaseURL() Option {
+	return func(site *Site) {
+		site.detectBaseURL = true
+	}
+}
+
+// ReloadConfigEvery enable hot-reload of the config file. The config file is checked
+// for modification every interval, and the site is reloaded if it's modified.
+// It's useful where file system notifications are not available, otherwise use WatchConfig.
+// Use Site.Close to stop watching.
+func ReloadConfigEvery(interval time.Duration) Option {
+	return func(site *Site) {
+		site.watchInterval = interval
+	}
+}
+
+// WatchConfig enable hot-reload of the config file using file system notifications.
+// See Site.WatchConfig for more detail.
+func WatchConfig() Option {
+	return func(site *Site) {
+		site.watchConfigFile = true
+	}
+}
+
+// LogRequests logs all the served requests into w in the given format,
+// the format is default to LogFormatJSON if not provided.
+func LogRequests(w io.Writer, format LogFormat) Option {
+	return func(site *Site) {
+		site.middlewares = append(site.middlewares, AccessLog(w, format))
+	}
+}
+
+// Compression enables gzip or deflate compression of the responses, same as `compress: true` in the config.
+func Compression(opts ...CompressOption) Option {
+	return func(site *Site) {
+		site.Compress = true
+		site.compressOpts = opts
+	}
+}
+
+// WithLogger set the logger of the site, default to a logger writes via the standard log package.
+func WithLogger(l Logger) Option {
+	return func(site *Site) {
+		if l != nil {
+			site.logger = l
+		}
+	}
+}
+
+// BotPatterns override the user agent patterns for detecting bots/crawlers via `.IsBot` of PageData.
+// The patterns are matched as case-insensitive substrings, i.e: "googlebot".
+func BotPatterns(patterns ...string) Option {
+	return func(site *Site) {
+		site.botPatterns = patterns
+	}
+}
+
+// WithFS set the file system for reading the config, templates and data files,
+// i.e: an embed.FS for single-binary deployment. Default to the OS file system.
+// Note that the static site is still generated into the OS file system.
+func WithFS(fsys fs.FS) Option {
+	return func(site *Site) {
+		if fsys != nil {
+			site.fs = fsys
+		}
+	}
+}
+
+// WithContentStore read the config, templates and data files from the given store instead of the file system,
+// i.e: NewMemoryStore for tests or a database backed store. See ContentStore for the limitations.
+func WithContentStore(store ContentStore) Option {
+	return func(site *Site) {
+		if store != nil {
+			site.fs = storeFS{store: store}
+		}
+	}
+}
+
+// Assets serve the files of the dir under the URL prefix, i.e: Assets("/assets/", "web/assets").
+// The `asset` func returns the fingerprinted URL of an asset base on its content hash,
+// i.e: [[asset "css/app.css"]] -> /assets/css/app.3f2a1b9c.css.
+// Files with hash in their names are cached as immutable, the others are cached for an hour.
+func Assets(urlPrefix string, dir string) Option {
+	return func(site *Site) {
+		site.assets = append(site.assets, &assetDir{
+			prefix: urlPrefix,
+			dir:    dir,
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package tiny
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Redirect maps an old path to a new path.
+	// From can be an exact path, a path ends with * for prefix matching
+	// or a regular expression starts with ^. The matched wildcard or
+	// the regular expression groups can be referred in To as * or $1, $2...
+	Redirect struct {
+		From   string `yaml:"from"`
+		To     string `yaml:"to"`
+		Status int    `yaml:"status"`
+
+		re *regexp.Regexp
+	}
+)
+
+// loadRedirects load the redirects from a YAML or CSV (from,to,status) file.
+func loadRedirects(fsys fs.FS, f string) ([]Redirect, error) {
+	b, err := fs.ReadFile(fsys, cleanPath(f))
+	if err != nil {
+		return nil, err
+	}
+	redirects := make([]Redirect, 0)
+	if strings.ToLower(filepath.Ext(f)) == ".csv" {
+		reader := csv.NewReader(strings.NewReader(string(b)))
+		reader.FieldsPerRecord = -1
+		reader.Comment = '#'
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, rec := range records {
+			if len(rec) < 2 {
+				return nil, fmt.Errorf("redirect: line %d, want from,to[,status]", i+1)
+			}
+			rd := Redirect{From: strings.TrimSpace(rec[0]), To: strings.TrimSpace(rec[1])}
+			if len(rec) > 2 && strings.TrimSpace(rec[2]) != "" {
+				if rd.Status, err = strconv.Atoi(strings.TrimSpace(rec[2])); err != nil {
+					return nil, fmt.Errorf("redirect: line %d, invalid status, err: %w", i+1, err)
+				}
+			}
+			redirects = append(redirects, rd)
+		}
+	} else if err := yaml.Unmarshal(b, &redirects); err != nil {
+		return nil, err
+	}
+	for i := range redirects {
+		if err := redirects[i].init(); err != nil {
+			return nil, err
+		}
+	}
+	return redirects, nil
+}
+
+func (rd *Redirect) init() error {
+	if rd.From == "" || rd.To == "" {
+		return fmt.Errorf("redirect: from and to are required, from: %s, to: %s", rd.From, rd.To)
+	}
+	if rd.Status == 0 {
+		rd.Status = http.StatusMovedPermanently
+	}
+	switch rd.Status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return fmt.Errorf("redirect: %s, invalid status: %d", rd.From, rd.Status)
+	}
+	if strings.HasPrefix(rd.From, "^") {
+		re, err := regexp.Compile(rd.From)
+		if err != nil {
+			return fmt.Errorf("redirect: %s, err: %w", rd.From, err)
+		}
+		rd.re = re
+	}
+	return nil
+}
+
+// match return the target location if the given path matches the redirect.
+func (rd Redirect) match(pth string) (string, bool) {
+	switch {
+	case rd.re != nil:
+		if !rd.re.MatchString(pth) {
+			return "", false
+		}
+		return rd.re.ReplaceAllString(pth, rd.To), true
+	case strings.HasSuffix(rd.From, "*"):
+		prefix := strings.TrimSuffix(rd.From, "*")
+		if !strings.HasPrefix(pth, prefix) {
+			return "", false
+		}
+		return strings.Replace(rd.To, "*", strings.TrimPrefix(pth, prefix), 1), true
+	}
+	return rd.To, pth == rd.From
+}
+
+// Redirects provides middleware for redirecting requests matched the given redirects.
+// Redirects are matched in order, the query string is preserved if the target has none.
+func Redirects(redirects []Redirect) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			for _, rd := range redirects {
+				if to, ok := rd.match(r.URL.Path); ok {
+					if r.URL.RawQuery != "" && !strings.Contains(to, "?") {
+						to += "?" + r.URL.RawQuery
+					}
+					http.Redirect(rw, r, to, rd.Status)
+					return
+				}
+			}
+			h.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package tiny_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirects(t *testing.T) {
+	config := `
+redirects: file://%s
+pages:
+  index:
+    path: /
+    components: [index.html]
+`
+	files := map[string]string{
+		"index.html": `index`,
+		"redirects.yml": `
+- from: /old
+  to: /new
+- from: /about-us
+  to: /about
+  status: 302
+- from: /blog/*
+  to: /posts/*
+- from: ^/p/(\d+)$
+  to: /posts/$1
+  status: 308
+`,
+		"redirects.csv": "/old,/new\n/about-us,/about,302\n",
+		"conflict.yml": `
+- from: /
+  to: /home
+`,
+	}
+	cases := []struct {
+		file     string
+		path     string
+		code     int
+		location string
+	}{
+		{file: "redirects.yml", path: "/old", code: http.StatusMovedPermanently, location: "/new"},
+		{file: "redirects.yml", path: "/old?x=1", code: http.StatusMovedPermanently, location: "/new?x=1"},
+		{file: "redirects.yml", path: "/about-us", code: http.StatusFound, location: "/about"},
+		{file: "redirects.yml", path: "/blog/2021/hello", code: http.StatusMovedPermanently, location: "/posts/2021/hello"},
+		{file: "redirects.yml", path: "/p/123", code: http.StatusPermanentRedirect, location: "/posts/123"},
+		{file: "redirects.yml", path: "/", code: http.StatusOK},
+		{file: "redirects.csv", path: "/old", code: http.StatusMovedPermanently, location: "/new"},
+		{file: "redirects.csv", path: "/about-us", code: http.StatusFound, location: "/about"},
+	}
+	for _, c := range cases {
+		t.Run(c.file+c.path, func(t *testing.T) {
+			site := newTestSite(t, fmt.Sprintf(config, c.file), files)
+			rw := serve(site, httptest.NewRequest(http.MethodGet, c.path, nil))
+			if rw.Code != c.code {
+				t.Errorf("got code=%d, want code=%d", rw.Code, c.code)
+			}
+			if got := rw.Header().Get("Location"); got != c.location {
+				t.Errorf("got location=%s, want location=%s", got, c.location)
+			}
+		})
+	}
+	t.Run("conflict with page", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("got no panic, want panic for redirect conflicts with page")
+			}
+		}()
+		newTestSite(t, fmt.Sprintf(config, "conflict.yml"), files)
+	})
+}
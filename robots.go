@@ -0,0 +1,111 @@
+package tiny
+
+import (
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// String return the robots.txt document, i.e:
+//
+//	User-agent: *
+//	Disallow: /admin/
+//
+//	Sitemap: https://example.com/sitemap.xml
+//
+// A user agent without rules is allowed to access everything.
+func (r RobotsTXT) String() string {
+	blocks := make([]string, 0, len(r.UserAgents)+1)
+	for _, ua := range r.UserAgents {
+		b := &strings.Builder{}
+		name := ua.UserAgent
+		if name == "" {
+			name = "*"
+		}
+		b.WriteString("User-agent: " + name + "\n")
+		if len(ua.Disallow) == 0 && len(ua.Allow) == 0 {
+			b.WriteString("Disallow:\n")
+		}
+		for _, v := range ua.Disallow {
+			b.WriteString("Disallow: " + v + "\n")
+		}
+		for _, v := range ua.Allow {
+			b.WriteString("Allow: " + v + "\n")
+		}
+		blocks = append(blocks, b.String())
+	}
+	if r.Sitemap != "" {
+		blocks = append(blocks, "Sitemap: "+r.Sitemap+"\n")
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// robotsTXTDataHandler return a DataHandler which provides the robots.txt from the page data.
+// All user agents are allowed if no rule is configured, and the Sitemap line
+// is derived from the base_url if the site has a sitemap page.
+func (site *Site) robotsTXTDataHandler(name string, data interface{}) (DataHandler, error) {
+	robots := RobotsTXT{}
+	if data != nil {
+		b, err := yaml.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(b, &robots); err != nil {
+			return nil, err
+		}
+	}
+	if len(robots.UserAgents) == 0 {
+		robots.UserAgents = []UserAgent{{UserAgent: "*"}}
+	}
+	return func(rw http.ResponseWriter, r *http.Request) interface{} {
+		rs := robots
+		if rs.Sitemap != "" {
+			return rs
+		}
+		base := site.getPageMetaData(name).BaseURL()
+		if base == "" && site.detectBaseURL {
+			base = requestBaseURL(r)
+		}
+		if sitemap := site.siteMapPath(); base != "" && sitemap != "" {
+			rs.Sitemap = absURL(base, sitemap)
+		}
+		return rs
+	}, nil
+}
+
+// siteMapPath return the path of the sitemap page if any.
+func (site *Site) siteMapPath() string {
+	if p, ok := site.Pages[PageSitemapXML]; ok {
+		return p.Path
+	}
+	for _, p := range site.Pages {
+		if p.DataType == DataTypeSiteMap {
+			return p.Path
+		}
+	}
+	return ""
+}
+
+// robotsTXTPath return the path of the robots.txt page if any.
+func (site *Site) robotsTXTPath() string {
+	if p, ok := site.Pages[PageRobotsTxt]; ok {
+		return p.Path
+	}
+	for _, p := range site.Pages {
+		if p.DataType == DataTypeRobots {
+			return p.Path
+		}
+	}
+	return ""
+}
+
+func renderRobotsTXT(rw http.ResponseWriter, data interface{}) error {
+	robots, ok := data.(RobotsTXT)
+	if !ok {
+		return NewError(http.StatusInternalServerError, "invalid robots.txt data: %T", data)
+	}
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, err := rw.Write([]byte(robots.String()))
+	return err
+}
@@ -0,0 +1,77 @@
+package tiny_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestRobotsTXTString(t *testing.T) {
+	robots := tiny.RobotsTXT{
+		UserAgents: []tiny.UserAgent{
+			{UserAgent: "*", Disallow: []string{"/admin/", "/tmp/"}, Allow: []string{"/admin/public/"}},
+			{UserAgent: "Googlebot"},
+		},
+		Sitemap: "https://example.com/sitemap.xml",
+	}
+	want := "User-agent: *\nDisallow: /admin/\nDisallow: /tmp/\nAllow: /admin/public/\n\n" +
+		"User-agent: Googlebot\nDisallow:\n\n" +
+		"Sitemap: https://example.com/sitemap.xml\n"
+	if got := robots.String(); got != want {
+		t.Errorf("got robots.txt=%q, want robots.txt=%q", got, want)
+	}
+}
+
+func TestRobotsTXTPage(t *testing.T) {
+	site := newTestSite(t, `
+metadata:
+  base_url: https://example.com
+pages:
+  robots:
+    path: /robots.txt
+    data_type: robots
+    data:
+      user_agents:
+        - user_agent: "*"
+          disallow: [/admin/, /private/]
+        - user_agent: BadBot
+          disallow: [/]
+  sitemap:
+    path: /sitemap.xml
+    data_type: sitemap
+  default:
+    path: /default/robots.txt
+    data_type: robots
+    metadata:
+      base_url: ""
+`, map[string]string{})
+	cases := []struct {
+		path string
+		body string
+	}{
+		{
+			path: "/robots.txt",
+			body: "User-agent: *\nDisallow: /admin/\nDisallow: /private/\n\n" +
+				"User-agent: BadBot\nDisallow: /\n\n" +
+				"Sitemap: https://example.com/sitemap.xml\n",
+		},
+		{
+			path: "/default/robots.txt",
+			body: "User-agent: *\nDisallow:\n",
+		},
+	}
+	for _, c := range cases {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if rw.Code != http.StatusOK {
+			t.Fatalf("path=%s, got code=%d, want code=%d", c.path, rw.Code, http.StatusOK)
+		}
+		if got := rw.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+			t.Errorf("path=%s, got Content-Type=%s, want text/plain", c.path, got)
+		}
+		if got := rw.Body.String(); got != c.body {
+			t.Errorf("path=%s, got body=%q, want body=%q", c.path, got, c.body)
+		}
+	}
+}
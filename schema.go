@@ -0,0 +1,208 @@
+package tiny
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// Schema is a subset of JSON Schema for validating the JSON data of the pages.
+	// Supported keywords: type, enum, const, properties, required, additionalProperties,
+	// items, minItems, maxItems, minLength, maxLength, pattern, minimum, maximum.
+	Schema struct {
+		Type                 interface{}        `json:"type"`
+		Enum                 []interface{}      `json:"enum"`
+		Const                interface{}        `json:"const"`
+		Properties           map[string]*Schema `json:"properties"`
+		Required             []string           `json:"required"`
+		AdditionalProperties *bool              `json:"additionalProperties"`
+		Items                *Schema            `json:"items"`
+		MinItems             *int               `json:"minItems"`
+		MaxItems             *int               `json:"maxItems"`
+		MinLength            *int               `json:"minLength"`
+		MaxLength            *int               `json:"maxLength"`
+		Pattern              string             `json:"pattern"`
+		Minimum              *float64           `json:"minimum"`
+		Maximum              *float64           `json:"maximum"`
+
+		re *regexp.Regexp
+	}
+)
+
+// loadSchema read the JSON schema from the given file.
+func loadSchema(fsys fs.FS, f string) (*Schema, error) {
+	b, err := fs.ReadFile(fsys, cleanPath(f))
+	if err != nil {
+		return nil, err
+	}
+	schema := &Schema{}
+	if err := json.Unmarshal(b, schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %s, err: %w", f, err)
+	}
+	if err := schema.compile(); err != nil {
+		return nil, fmt.Errorf("invalid schema: %s, err: %w", f, err)
+	}
+	return schema, nil
+}
+
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.re = re
+	}
+	for _, p := range s.Properties {
+		if err := p.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// Validate validate the decoded JSON data against the schema,
+// the error reports the path of the failing field, i.e: $.posts[1].title.
+func (s *Schema) Validate(data interface{}) error {
+	return s.validate("$", data)
+}
+
+func (s *Schema) validate(pth string, data interface{}) error {
+	if s.Type != nil && !s.matchType(data) {
+		return fmt.Errorf("%s: expected type %v, got %s", pth, s.Type, jsonType(data))
+	}
+	if s.Const != nil && !reflect.DeepEqual(s.Const, data) {
+		return fmt.Errorf("%s: expected %v, got %v", pth, s.Const, data)
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, v := range s.Enum {
+			found = found || reflect.DeepEqual(v, data)
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", pth, data, s.Enum)
+		}
+	}
+	switch v := data.(type) {
+	case map[string]interface{}:
+		return s.validateObject(pth, v)
+	case []interface{}:
+		return s.validateArray(pth, v)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fmt.Errorf("%s: length %d is less than %d", pth, n, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fmt.Errorf("%s: length %d is greater than %d", pth, n, *s.MaxLength)
+		}
+		if s.re != nil && !s.re.MatchString(v) {
+			return fmt.Errorf("%s: %q does not match pattern %s", pth, v, s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("%s: %v is less than %v", pth, v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("%s: %v is greater than %v", pth, v, *s.Maximum)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(pth string, obj map[string]interface{}) error {
+	for _, k := range s.Required {
+		if _, ok := obj[k]; !ok {
+			return fmt.Errorf("%s.%s: is required", pth, k)
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	// validate in order, so that the reported error is deterministic.
+	sort.Strings(keys)
+	for _, k := range keys {
+		p, ok := s.Properties[k]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return fmt.Errorf("%s.%s: additional property is not allowed", pth, k)
+			}
+			continue
+		}
+		if err := p.validate(pth+"."+k, obj[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateArray(pth string, arr []interface{}) error {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		return fmt.Errorf("%s: %d items is less than %d", pth, len(arr), *s.MinItems)
+	}
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		return fmt.Errorf("%s: %d items is greater than %d", pth, len(arr), *s.MaxItems)
+	}
+	if s.Items == nil {
+		return nil
+	}
+	for i, item := range arr {
+		if err := s.Items.validate(fmt.Sprintf("%s[%d]", pth, i), item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchType report whether the data matches the type, which can be a type name or a list of type names.
+func (s *Schema) matchType(data interface{}) bool {
+	types := []string{}
+	switch t := s.Type.(type) {
+	case string:
+		types = append(types, t)
+	case []interface{}:
+		for _, v := range t {
+			types = append(types, fmt.Sprintf("%v", v))
+		}
+	}
+	actual := jsonType(data)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType return the JSON type name of the decoded JSON data.
+func jsonType(data interface{}) string {
+	switch v := data.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return strings.ToLower(reflect.TypeOf(data).Kind().String())
+}
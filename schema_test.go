@@ -0,0 +1,80 @@
+package tiny_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPageSchema(t *testing.T) {
+	config := `
+pages:
+  posts:
+    path: /posts
+    components: [posts.html]
+    data: file://posts.json
+    data_type: json
+    schema: file://schema.json
+`
+	schema := `{
+  "type": "object",
+  "required": ["posts"],
+  "properties": {
+    "posts": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["title"],
+        "properties": {
+          "title": {"type": "string", "minLength": 1},
+          "status": {"enum": ["draft", "published"]},
+          "views": {"type": "integer", "minimum": 0}
+        }
+      }
+    }
+  }
+}`
+	t.Run("valid", func(t *testing.T) {
+		site := newTestSite(t, config, map[string]string{
+			"posts.html":  `[[range .Data.posts]][[.title]] [[end]]`,
+			"schema.json": schema,
+			"posts.json":  `{"posts": [{"title": "hello", "status": "draft", "views": 1}, {"title": "world"}]}`,
+		})
+		rw := serve(site, httptest.NewRequest(http.MethodGet, "/posts", nil))
+		if got := rw.Body.String(); got != "hello world " {
+			t.Errorf("got body=%s, want body=hello world ", got)
+		}
+	})
+	cases := []struct {
+		name  string
+		data  string
+		field string
+	}{
+		{name: "wrong type", data: `{"posts": [{"title": "hello"}, {"title": 1}]}`, field: "$.posts[1].title"},
+		{name: "missing required", data: `{"posts": [{"status": "draft"}]}`, field: "$.posts[0].title"},
+		{name: "not in enum", data: `{"posts": [{"title": "hello", "status": "deleted"}]}`, field: "$.posts[0].status"},
+		{name: "below minimum", data: `{"posts": [{"title": "hello", "views": -1}]}`, field: "$.posts[0].views"},
+		{name: "not integer", data: `{"posts": [{"title": "hello", "views": 1.5}]}`, field: "$.posts[0].views"},
+		{name: "missing root field", data: `{}`, field: "$.posts"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Fatalf("got no panic, want panic for invalid data")
+				}
+				if msg := fmt.Sprint(r); !strings.Contains(msg, c.field+":") {
+					t.Errorf("got error=%s, want error reports field %s", msg, c.field)
+				}
+			}()
+			newTestSite(t, config, map[string]string{
+				"posts.html":  `posts`,
+				"schema.json": schema,
+				"posts.json":  c.data,
+			})
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package tiny
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSessionCookie is the default name of the session cookie.
+	DefaultSessionCookie = "session"
+	// DefaultSessionMaxAge is the default max age of the sessions.
+	DefaultSessionMaxAge = 24 * time.Hour
+	// minSessionSecretLen is the min length of the secret for signing the session cookies.
+	minSessionSecretLen = 16
+)
+
+type (
+	// SessionStore loads and saves the claims of the user sessions.
+	SessionStore interface {
+		// Get return the claims of the session of the request if any.
+		Get(r *http.Request) (claims interface{}, ok bool)
+		// Save save the claims to the session, nil claims clears the session.
+		Save(rw http.ResponseWriter, claims interface{}) error
+	}
+
+	// SessionConfig configures the signed cookie session store of the site.
+	SessionConfig struct {
+		// Cookie is name of the session cookie, default to DefaultSessionCookie.
+		Cookie string `yaml:"cookie"`
+		// Secret is the key for signing the session cookies, env variables are expanded, i.e: ${SESSION_SECRET}.
+		Secret string `yaml:"secret"`
+		// MaxAge is the max age of the sessions, default to DefaultSessionMaxAge.
+		MaxAge time.Duration `yaml:"max_age"`
+		Secure bool          `yaml:"secure"`
+	}
+
+	// CookieStore is a SessionStore keeps the claims in a signed cookie.
+	// The claims are encoded as JSON, hence they are decoded as generic values,
+	// i.e: map[string]interface{} for a struct. Note that the claims are signed but not encrypted.
+	CookieStore struct {
+		Name   string
+		MaxAge time.Duration
+		Secure bool
+		secret []byte
+	}
+
+	// sessionPayload is the signed content of the session cookie.
+	sessionPayload struct {
+		Claims  interface{} `json:"c"`
+		Expires int64       `json:"e"`
+	}
+
+	sessionContextKey struct{}
+)
+
+// NewCookieStore return a new signed cookie session store using the given secret, the secret must be at least 16 bytes.
+func NewCookieStore(secret string) (*CookieStore, error) {
+	if len(secret) < minSessionSecretLen {
+		return nil, fmt.Errorf("session secret must be at least %d bytes", minSessionSecretLen)
+	}
+	return &CookieStore{
+		Name:   DefaultSessionCookie,
+		MaxAge: DefaultSessionMaxAge,
+		secret: []byte(secret),
+	}, nil
+}
+
+// Get return the claims of the session cookie if it's valid and not expired.
+func (s *CookieStore) Get(r *http.Request) (interface{}, bool) {
+	ck, err := r.Cookie(s.Name)
+	if err != nil {
+		return nil, false
+	}
+	i := strings.LastIndex(ck.Value, ".")
+	if i < 0 {
+		return nil, false
+	}
+	payload, sig := ck.Value[:i], ck.Value[i+1:]
+	want, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(want, s.sign(payload)) {
+		return nil, false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, false
+	}
+	p := sessionPayload{}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, false
+	}
+	if time.Now().Unix() > p.Expires || p.Claims == nil {
+		return nil, false
+	}
+	return p.Claims, true
+}
+
+// Save write the claims to the signed session cookie, nil claims removes the cookie.
+func (s *CookieStore) Save(rw http.ResponseWriter, claims interface{}) error {
+	ck := &http.Cookie{
+		Name:     s.Name,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.Secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if claims == nil {
+		ck.MaxAge = -1
+		http.SetCookie(rw, ck)
+		return nil
+	}
+	expires := time.Now().Add(s.MaxAge)
+	b, err := json.Marshal(sessionPayload{Claims: claims, Expires: expires.Unix()})
+	if err != nil {
+		return err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	ck.Value = payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+	ck.Expires = expires
+	ck.MaxAge = int(s.MaxAge.Seconds())
+	http.SetCookie(rw, ck)
+	return nil
+}
+
+func (s *CookieStore) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// Sessions provides middleware for loading the claims of the session into the request context,
+// they can be read via SessionInfo, i.e: as the AuthInfoFunc of the site.
+func Sessions(store SessionStore) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if claims, ok := store.Get(r); ok {
+				r = r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, claims))
+			}
+			h.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// SessionInfo return the claims of the session loaded by the Sessions middleware, it's an AuthInfoFunc.
+func SessionInfo(ctx context.Context) (interface{}, bool) {
+	claims := ctx.Value(sessionContextKey{})
+	return claims, claims != nil
+}
+
+// SessionStore return the session store of the site if configured, see WithSessionStore.
+func (site *Site) SessionStore() SessionStore {
+	if cur := site.current(); cur != site {
+		return cur.SessionStore()
+	}
+	return site.sessions
+}
+
+// setupSession create the signed cookie session store from the config, if the store is not provided via option.
+// The session claims are used as the auth info if no auth info func is provided.
+func (site *Site) setupSession() error {
+	if site.sessions == nil && site.Session != nil {
+		store, err := NewCookieStore(os.ExpandEnv(site.Session.Secret))
+		if err != nil {
+			return err
+		}
+		if site.Session.Cookie != "" {
+			store.Name = site.Session.Cookie
+		}
+		if site.Session.MaxAge > 0 {
+			store.MaxAge = site.Session.MaxAge
+		}
+		store.Secure = site.Session.Secure
+		site.sessions = store
+	}
+	if site.sessions == nil {
+		return nil
+	}
+	if site.authInfo == nil {
+		site.authInfo = SessionInfo
+	}
+	site.middlewares = append(site.middlewares, Sessions(site.sessions))
+	return nil
+}
@@ -0,0 +1,122 @@
+package tiny_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestCookieStore(t *testing.T) {
+	store, err := tiny.NewCookieStore("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := func(s *tiny.CookieStore, claims interface{}) *http.Cookie {
+		rw := httptest.NewRecorder()
+		if err := s.Save(rw, claims); err != nil {
+			t.Fatal(err)
+		}
+		cookies := rw.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("got %d cookies, want 1 cookie", len(cookies))
+		}
+		return cookies[0]
+	}
+	get := func(s *tiny.CookieStore, ck *http.Cookie) (interface{}, bool) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(ck)
+		return s.Get(r)
+	}
+	ck := save(store, map[string]interface{}{"name": "jack", "admin": true})
+	if !ck.HttpOnly || ck.Name != tiny.DefaultSessionCookie {
+		t.Errorf("got cookie=%v, want http only cookie named %s", ck, tiny.DefaultSessionCookie)
+	}
+	claims, ok := get(store, ck)
+	if want := map[string]interface{}{"name": "jack", "admin": true}; !ok || !reflect.DeepEqual(claims, want) {
+		t.Errorf("got claims=%v, ok=%v, want claims=%v, ok=true", claims, ok, want)
+	}
+	t.Run("tampered", func(t *testing.T) {
+		payload := ck.Value[:strings.LastIndex(ck.Value, ".")]
+		forged := save(store, map[string]interface{}{"name": "jack", "admin": false})
+		// swap the signature of another session.
+		tampered := *ck
+		tampered.Value = payload + forged.Value[strings.LastIndex(forged.Value, "."):]
+		if claims, ok := get(store, &tampered); ok {
+			t.Errorf("got claims=%v, want tampered cookie rejected", claims)
+		}
+		tampered.Value = "x" + ck.Value[1:]
+		if claims, ok := get(store, &tampered); ok {
+			t.Errorf("got claims=%v, want tampered cookie rejected", claims)
+		}
+	})
+	t.Run("other secret", func(t *testing.T) {
+		other, _ := tiny.NewCookieStore("another secret of the other site")
+		if claims, ok := get(other, ck); ok {
+			t.Errorf("got claims=%v, want cookie of other secret rejected", claims)
+		}
+	})
+	t.Run("expired", func(t *testing.T) {
+		store.MaxAge = -time.Minute
+		defer func() { store.MaxAge = tiny.DefaultSessionMaxAge }()
+		if claims, ok := get(store, save(store, "jack")); ok {
+			t.Errorf("got claims=%v, want expired cookie rejected", claims)
+		}
+	})
+	t.Run("short secret", func(t *testing.T) {
+		if _, err := tiny.NewCookieStore("short"); err == nil {
+			t.Errorf("got err=nil, want error for short secret")
+		}
+	})
+}
+
+func TestSessionLogin(t *testing.T) {
+	os.Setenv("TINY_TEST_SESSION_SECRET", "0123456789abcdef0123456789abcdef")
+	defer os.Unsetenv("TINY_TEST_SESSION_SECRET")
+	site := newTestSite(t, `
+login: /login
+session:
+  cookie: sid
+  secret: ${TINY_TEST_SESSION_SECRET}
+pages:
+  login:
+    path: /login
+    components: [login.html]
+  admin:
+    path: /admin
+    components: [admin.html]
+    auth: true
+`, map[string]string{
+		"login.html": `login`,
+		"admin.html": `hello [[index .User "name"]]`,
+	}, tiny.LoginHandler(func(rw http.ResponseWriter, r *http.Request) (interface{}, error) {
+		return map[string]interface{}{"name": r.FormValue("name")}, nil
+	}))
+	form := url.Values{"name": {"jack"}}
+	r := httptest.NewRequest(http.MethodPost, "/login?redirect=/admin", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rw := serve(site, r)
+	cookies := rw.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "sid" {
+		t.Fatalf("got cookies=%v, want session cookie sid", cookies)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/admin", nil)
+	r.AddCookie(cookies[0])
+	rw = serve(site, r)
+	if got, want := rw.Body.String(), "hello jack"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+	// tampered session is treated as not logged in.
+	r = httptest.NewRequest(http.MethodGet, "/admin", nil)
+	r.AddCookie(&http.Cookie{Name: "sid", Value: "e30." + strings.Split(cookies[0].Value, ".")[1]})
+	rw = serve(site, r)
+	if rw.Code != http.StatusFound || rw.Header().Get("Location") != "/login?redirect=/admin" {
+		t.Errorf("got code=%d, location=%s, want redirect to login", rw.Code, rw.Header().Get("Location"))
+	}
+}
@@ -1,14 +1,19 @@
 package tiny
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
 	"path"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -24,10 +29,34 @@ const (
 	PageError      = "500"
 	PageRobotsTxt  = "robots.txt"
 	PageSitemapXML = "sitemap.xml"
+	PageOpenSearch = "opensearch.xml"
 	filePrefix     = "file://"
 
 	DefaultDelimLeft  = "[["
 	DefaultDelimRight = "]]"
+
+	// limits of the render_template func to prevent abuse.
+	maxRenderTemplateDepth = 10
+	maxRenderTemplateSize  = 1 << 20
+	// streamFlushSize is the size of the content of the streamed pages is buffered before flushed.
+	streamFlushSize = 4 << 10
+
+	DataTypeJSON       = "json"
+	DataTypeOpenSearch = "opensearch"
+	DataTypeSiteMap    = "sitemap"
+	DataTypeRobots     = "robots"
+)
+
+var (
+	// defaultBotPatterns are the user agent patterns of the common bots/crawlers.
+	defaultBotPatterns = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "headlesschrome"}
+
+	// builtinRenderers render pages of the built-in data types without templates.
+	builtinRenderers = map[string]func(rw http.ResponseWriter, data interface{}) error{
+		DataTypeOpenSearch: renderOpenSearch,
+		DataTypeSiteMap:    renderSiteMap,
+		DataTypeRobots:     renderRobotsTXT,
+	}
 )
 
 type (
@@ -35,7 +64,11 @@ type (
 	// this is just for quickly create a small site like blog.
 	// Note that templates use tag [[ ]] by default.
 	Site struct {
-		MaxAge     time.Duration       `yaml:"max_age"`
+		MaxAge time.Duration `yaml:"max_age"`
+		// StaticMaxAge is the default max age of the static files/dirs, default to MaxAge.
+		StaticMaxAge time.Duration `yaml:"static_max_age"`
+		// PageMaxAge is the default max age of the rendered pages, they are not cached by default.
+		PageMaxAge time.Duration       `yaml:"page_max_age"`
 		MetaData   MetaData            `yaml:"metadata"`
 		Reload     bool                `yaml:"reload"`
 		Login      string              `yaml:"login"`
@@ -45,30 +78,120 @@ type (
 		DelimLeft  string              `yaml:"delim_left"`
 		DelimRight string              `yaml:"delim_right"`
 		StaticSite StaticSite          `yaml:"static_site"`
+		// Home is name of the page to be served at "/" in addition to its own path.
+		Home string `yaml:"home"`
+		// TimeZone is the time zone of the time funcs such as year and today, default to the local time zone.
+		TimeZone string `yaml:"timezone"`
+		// Preconnect and DNSPrefetch are the default origins of all pages
+		// to be sent as Link headers, i.e: Link: <https://fonts.gstatic.com>; rel=preconnect.
+		Preconnect  []string `yaml:"preconnect"`
+		DNSPrefetch []string `yaml:"dns_prefetch"`
+		// Redirects is a YAML or CSV file declares the redirects, i.e: file://redirects.yml.
+		Redirects string `yaml:"redirects"`
+		// Languages are the language variants of the site, used to populate the hreflang alternates.
+		Languages       []Language `yaml:"languages"`
+		DefaultLanguage string     `yaml:"default_language"`
+		// Headers are the default headers of all pages, i.e: X-Frame-Options: DENY.
+		Headers map[string]string `yaml:"headers"`
+		// Compress enables gzip or deflate compression of the responses, see Compress middleware.
+		Compress bool `yaml:"compress"`
+		// RenderTimeout is the max duration of rendering a page, the request fails with 500 if it's exceeded.
+		// Streamed pages are limited too, they are cut off if the timeout is exceeded after they are written.
+		// Default to no limit.
+		RenderTimeout time.Duration `yaml:"render_timeout"`
+		// MaxRenderDepth is the max nesting depth of the render_template func, default to 10.
+		MaxRenderDepth int `yaml:"max_render_depth"`
+		// MaintenanceRetryAfter is the Retry-After of the maintenance page, default to 1 hour, see Maintenance.
+		MaintenanceRetryAfter time.Duration `yaml:"maintenance_retry_after"`
+		// CSRF enables the CSRF protection of the unsafe requests, see CSRF middleware.
+		CSRF bool `yaml:"csrf"`
+		// Session configures the signed cookie sessions, the session claims are used as the auth info.
+		Session *SessionConfig `yaml:"session"`
+		// Tracking are the analytics/tracking snippets, rendered as PageData.Tracking if the visitor gave consent.
+		Tracking Tracking `yaml:"tracking"`
 
-		router    *mux.Router
-		templates map[string]*template.Template
-		mu        sync.RWMutex
-		funcs     map[string]interface{}
-		authInfo  AuthInfoFunc
-		errors    map[int]string
+		router        *mux.Router
+		handler       http.Handler
+		logger        Logger
+		fs            fs.FS
+		botPatterns   []string
+		assets        []*assetDir
+		compress      func(http.Handler) http.Handler
+		compressOpts  []CompressOption
+		middlewares   []func(http.Handler) http.Handler
+		templates     map[string]*template.Template
+		mu            sync.RWMutex
+		fileLocks     sync.Map
+		funcs         map[string]interface{}
+		authInfo      AuthInfoFunc
+		loginFunc     LoginFunc
+		maintenance   string
+		sessions      SessionStore
+		errors        map[int]string
+		transforms    []ResponseTransformFunc
+		flags         FlagsFunc
+		detectBaseURL bool
+		redirects     []Redirect
+		buildInfo     Build
+		includeTags   []string
+		excludeTags   []string
+		// path and options are used to reload the config.
+		path            string
+		options         []Option
+		watchInterval   time.Duration
+		watchConfigFile bool
+		stopWatch       chan struct{}
+		// dataHandlers are the data handlers set by users, next is the reloaded site.
+		dataHandlers map[string]DataHandler
+		next         *Site
 	}
 
 	// Page represent a web page.
 	Page struct {
-		Path        string        `yaml:"path"`
-		Layout      string        `yaml:"layout"`
-		Components  []string      `yaml:"components"`
-		MetaData    MetaData      `yaml:"metadata"`
-		Auth        bool          `yaml:"auth"`
-		DelimLeft   string        `yaml:"delim_left"`
-		DelimRight  string        `yaml:"delim_right"`
-		Data        interface{}   `yaml:"data"`
-		DataType    string        `yaml:"data_type"`
-		MaxAge      time.Duration `yaml:"max_age"`
-		DataHandler DataHandler   `yaml:"-"`
+		Path        string         `yaml:"path"`
+		Layout      string         `yaml:"layout"`
+		Components  []string       `yaml:"components"`
+		MetaData    MetaData       `yaml:"metadata"`
+		Auth        bool           `yaml:"auth"`
+		DelimLeft   string         `yaml:"delim_left"`
+		DelimRight  string         `yaml:"delim_right"`
+		Data        interface{}    `yaml:"data"`
+		DataType    string         `yaml:"data_type"`
+		MaxAge      time.Duration  `yaml:"max_age"`
+		RateLimit   *PageRateLimit `yaml:"rate_limit"`
+		Preconnect  []string       `yaml:"preconnect"`
+		DNSPrefetch []string       `yaml:"dns_prefetch"`
+		Tags        []string       `yaml:"tags"`
+		// AllowedFuncs restricts the funcs available to the page templates if provided.
+		AllowedFuncs []string `yaml:"allowed_funcs"`
+		// Headers are the headers of the page, they override the site headers with the same names.
+		Headers map[string]string `yaml:"headers"`
+		// Schema is the JSON schema file for validating the JSON data of the page, i.e: file://schema.json.
+		Schema string `yaml:"schema"`
+		// Methods are the HTTP methods the page accepts, default to GET.
+		Methods []string `yaml:"methods"`
+		// JSON serves the page data as JSON instead of rendering the template if the client prefers JSON,
+		// i.e: Accept: application/json.
+		JSON bool `yaml:"json"`
+		// AuthMode controls how the unauthenticated requests are rejected: redirect or status (401),
+		// default to redirect the browser navigations and 401 for the API/AJAX requests, see AuthRequired.
+		AuthMode string `yaml:"auth_mode"`
+		// Variants are served instead of the page if the request matches, the first matched variant wins.
+		Variants []PageVariant `yaml:"variants"`
+		// Stream writes the rendered page to the client as it's rendered instead of buffering it,
+		// it's enabled automatically if the page data is a channel. The content is flushed in chunks,
+		// or at the flush calls of the template, i.e: [[range .Data]]...[[flush]][[end]].
+		// Note that response transforms are not applied to streamed pages.
+		Stream      bool        `yaml:"stream"`
+		DataHandler DataHandler `yaml:"-"`
 
 		isStatic bool
+		isDir    bool
+	}
+	// PageRateLimit limits number of requests per second of each client to a page.
+	PageRateLimit struct {
+		RPS   float64 `yaml:"rps"`
+		Burst int     `yaml:"burst"`
 	}
 	// PageData hold basic data of a web page.
 	PageData struct {
@@ -77,10 +200,36 @@ type (
 		User          interface{}
 		Error         error
 		Cookies       map[string]*http.Cookie
+		Flags         map[string]bool
+		BuildInfo     Build
+		// URL is the URL of the current request.
+		URL *url.URL
+		// Query are the query params of the current request.
+		// If the DataHandler returns a PageData with Query, its values override the request ones key by key.
+		Query url.Values
+		// Vars are the path variables of the current request, i.e: slug of /blog/{slug}.
+		Vars map[string]string
+		// IsBot reports whether the request is from a bot/crawler, base on its User-Agent.
+		IsBot bool
+		// Alternates are the language variants of the page, available if languages are configured.
+		Alternates []Alternate
+		// CSRFToken is the token to be submitted with the forms if the CSRF protection is enabled.
+		CSRFToken string
+		// CSPNonce is the nonce of the request for the inline scripts and styles,
+		// the same as the one in the Content-Security-Policy header, i.e: <script nonce="[[.CSPNonce]]">.
+		CSPNonce string
+		// Tracking are the rendered tracking snippets of the site, empty if the visitor hasn't given consent.
+		Tracking template.HTML
 
 		// additional data return from DataHandler.
 		Data interface{}
 	}
+	// Build holds the build information of the site, usually set via ldflags.
+	Build struct {
+		Version   string
+		Commit    string
+		BuildTime string
+	}
 	SiteMapURL struct {
 		Loc        string
 		LastMod    time.Time
@@ -92,12 +241,14 @@ type (
 	}
 
 	UserAgent struct {
-		UserAgent string
-		Disallow  []string
-		Allow     []string
+		UserAgent string   `yaml:"user_agent"`
+		Disallow  []string `yaml:"disallow"`
+		Allow     []string `yaml:"allow"`
 	}
 	RobotsTXT struct {
-		UserAgents []UserAgent
+		UserAgents []UserAgent `yaml:"user_agents"`
+		// Sitemap is the absolute URL of the sitemap.
+		Sitemap string `yaml:"sitemap"`
 	}
 
 	// DataHandler is a custom handler for providing data to be used in page templates.
@@ -107,15 +258,78 @@ type (
 	SiteMapDataHandler   = func(rw http.ResponseWriter, r *http.Request) SiteMap
 	RobotsTXTDataHandler = func(rw http.ResponseWriter, r *http.Request) RobotsTXT
 	AuthInfoFunc         = func(context.Context) (interface{}, bool)
+	// LoginFunc processes the login form submissions, it usually verifies the credentials and sets the session cookie.
+	// The login fails if an error or nil claims is returned, return an Error to control the status, i.e: 401.
+	LoginFunc = func(rw http.ResponseWriter, r *http.Request) (claims interface{}, err error)
+	// FlagsFunc return the current state of feature flags.
+	FlagsFunc = func() map[string]bool
+
+	// ResponseTransformFunc modifies the rendered content of a HTML page before it is written to the client.
+	ResponseTransformFunc = func(b []byte, r *http.Request) []byte
+
+	// pageDataCache holds the assembled page data of a request by page name.
+	pageDataCache    map[string]PageData
+	pageDataCacheKey struct{}
 )
 
 // NewSite read site definition from yaml config file.
-// Panics if any error.
+// Panics if any error, use NewSiteFromFile to handle the error.
 func NewSite(path string, options ...Option) *Site {
-	b, err := os.ReadFile(path)
+	site, err := NewSiteFromFile(path, options...)
 	if err != nil {
 		log.Panic(err)
 	}
+	return site
+}
+
+// NewSiteFromFile read site definition from yaml config file.
+func NewSiteFromFile(path string, options ...Option) (*Site, error) {
+	site, err := loadSite(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	if site.watchInterval > 0 {
+		site.pollConfig(site.watchInterval)
+	}
+	if site.watchConfigFile {
+		if err := site.WatchConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return site, nil
+}
+
+// NewSiteFromBytes read site definition from the given yaml config.
+// Paths in the config are relative to the working directory.
+// The config can't be reloaded since there is no config file.
+func NewSiteFromBytes(b []byte, options ...Option) (*Site, error) {
+	site, err := newSite(b, "", options...)
+	if err != nil {
+		return nil, err
+	}
+	if site.watchInterval > 0 || site.watchConfigFile {
+		return nil, fmt.Errorf("watching config requires a config file")
+	}
+	return site, nil
+}
+
+// loadSite read site definition from yaml config file and setup its handlers and router.
+// The config file is read from the file system provided via WithFS if any.
+func loadSite(path string, options ...Option) (*Site, error) {
+	// apply the options to a probe site for getting the file system before parsing the config.
+	probe := Site{fs: osFS{}}
+	for _, opt := range options {
+		opt(&probe)
+	}
+	b, err := fs.ReadFile(probe.fs, cleanPath(path))
+	if err != nil {
+		return nil, err
+	}
+	return newSite(b, path, options...)
+}
+
+// newSite parse the site definition and setup its handlers and router.
+func newSite(b []byte, path string, options ...Option) (*Site, error) {
 	site := Site{
 		MetaData: map[string]interface{}{
 			"lang":          "en",
@@ -136,22 +350,43 @@ func NewSite(path string, options ...Option) *Site {
 			PageNotFound: {http.StatusNotFound},
 			PageError:    {http.StatusInternalServerError},
 		},
-		errors:     make(map[int]string),
-		mu:         sync.RWMutex{},
-		funcs:      funcs.FuncMap(),
-		templates:  make(map[string]*template.Template),
-		DelimLeft:  DefaultDelimLeft,
-		DelimRight: DefaultDelimRight,
-		MaxAge:     30 * 24 * time.Hour,
+		errors:      make(map[int]string),
+		mu:          sync.RWMutex{},
+		funcs:       funcs.FuncMap(),
+		templates:   make(map[string]*template.Template),
+		DelimLeft:   DefaultDelimLeft,
+		DelimRight:  DefaultDelimRight,
+		MaxAge:      30 * 24 * time.Hour,
+		path:        path,
+		options:     options,
+		logger:      stdLogger{},
+		fs:          osFS{},
+		botPatterns: defaultBotPatterns,
 	}
+	site.funcs["flag"] = site.flag
+	site.funcs["build_info"] = func() Build { return site.buildInfo }
+	site.funcs["render_template"] = site.renderTemplateFunc(nil, 0)
+	site.funcs["abs_url"] = func(p string) string { return absURL(site.MetaData.BaseURL(), p) }
+	site.funcs["suggest"] = func(p string, n int) []string { return funcs.Closest(p, site.pagePaths(), n) }
+	site.funcs["asset"] = site.assetURL
+	site.funcs["front_matter"] = site.frontMatter
+	// flush is bound per request for the streamed pages, see streamPage.
+	site.funcs["flush"] = func() string { return "" }
 	// parse config
 	if err := yaml.Unmarshal(b, &site); err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 	// apply user options
 	for _, opt := range options {
 		opt(&site)
 	}
+	if err := site.setupTimeZone(); err != nil {
+		return nil, err
+	}
+	// filter pages by tags
+	if err := site.filterPages(); err != nil {
+		return nil, err
+	}
 	// re-mapping error handlers
 	for p, errs := range site.Errors {
 		for _, err := range errs {
@@ -159,70 +394,256 @@ func NewSite(path string, options ...Option) *Site {
 		}
 	}
 	// setup handlers and routers
-	site.setupDataHandlers()
+	if err := site.setupDataHandlers(); err != nil {
+		return nil, err
+	}
+	if err := site.setupRedirects(); err != nil {
+		return nil, err
+	}
+	if err := site.setupAssets(); err != nil {
+		return nil, err
+	}
+	if err := site.setupTracking(); err != nil {
+		return nil, err
+	}
+	if err := site.setupSession(); err != nil {
+		return nil, err
+	}
+	if site.CSRF {
+		site.middlewares = append(site.middlewares, CSRF())
+	}
 	site.setupRouter()
 
 	// validate site config
 	if err := site.validateSite(); err != nil {
-		log.Panic(err)
+		return nil, err
 	}
-	return &site
+	return &site, nil
 }
 
-func (site *Site) setupDataHandlers() {
+// ReloadConfig re-read the config file and setup a new router from it,
+// the new router is swapped in only if the new config is valid.
+// Data handlers set via SetDataHandler are kept, and the config is read from the new config
+// once it's swapped in, i.e: by GenerateSiteMap and GenerateStaticSite.
+func (site *Site) ReloadConfig() error {
+	if site.path == "" {
+		return fmt.Errorf("reload config: no config file")
+	}
+	next, err := loadSite(site.path, site.options...)
+	if err != nil {
+		return err
+	}
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	for name, h := range site.dataHandlers {
+		// the page might be removed from the new config.
+		_ = next.setDataHandler(name, h)
+	}
+	next.StaticSite.Request.dynamicPathsHandlers = site.StaticSite.Request.dynamicPathsHandlers
+	if next.loginFunc == nil && site.loginFunc != nil && next.Login != "" {
+		// the login func was set via SetLoginHandler.
+		next.loginFunc = site.loginFunc
+		next.setupRouter()
+	}
+	site.router = next.router
+	site.handler = next.handler
+	site.compress = next.compress
+	site.next = next
+	return nil
+}
+
+// current return the site serves the requests, which is the reloaded site if the config was reloaded,
+// so that the config is read from the latest config.
+func (site *Site) current() *Site {
+	site.mu.RLock()
+	defer site.mu.RUnlock()
+	if site.next != nil {
+		return site.next
+	}
+	return site
+}
+
+// filterPages remove the pages which don't match the include/exclude tags.
+// Pages referenced as error pages must not be removed.
+func (site *Site) filterPages() error {
+	if len(site.includeTags) == 0 && len(site.excludeTags) == 0 {
+		return nil
+	}
+	for n, p := range site.Pages {
+		if (len(site.includeTags) == 0 || p.hasAnyTag(site.includeTags)) && !p.hasAnyTag(site.excludeTags) {
+			continue
+		}
+		if _, ok := site.Errors[n]; ok {
+			return fmt.Errorf("page: %s is an error page but is filtered out by tags", n)
+		}
+		delete(site.Pages, n)
+	}
+	return nil
+}
+
+func (site *Site) setupDataHandlers() error {
 	for n, p := range site.Pages {
 		n := n
 		p := p
-		if p.MaxAge <= 0 {
-			p.MaxAge = site.MaxAge
-		}
 		switch {
-		case p.DataType == "json":
+		case p.DataType == DataTypeJSON:
 			f, ok := p.Data.(string)
+			if !ok || !strings.HasPrefix(f, filePrefix) {
+				return fmt.Errorf("invalid data type, page: %s, data_type: %s, data: %v", n, p.DataType, p.Data)
+			}
+			h, err := site.jsonFileDataHandler(strings.TrimPrefix(f, filePrefix), strings.TrimPrefix(p.Schema, filePrefix))
+			if err != nil {
+				return fmt.Errorf("page: %s, err: %w", n, err)
+			}
+			site.setDataHandler(n, h)
+		case p.DataType == DataTypeOpenSearch:
+			searchURL, ok := p.Data.(string)
 			if !ok {
-				log.Panicf("invalid data type, page: %s, data: %v", n, p.Data)
+				return fmt.Errorf("invalid data type, page: %s, data_type: %s, data: %v", n, p.DataType, p.Data)
 			}
-			f = f[len(filePrefix):]
-			site.SetDataHandler(n, site.jsonFileDataHandler(f))
+			site.setDataHandler(n, site.openSearchDataHandler(n, searchURL))
+		case p.DataType == DataTypeSiteMap:
+			site.setDataHandler(n, site.siteMapDataHandler(n))
+		case p.DataType == DataTypeRobots:
+			h, err := site.robotsTXTDataHandler(n, p.Data)
+			if err != nil {
+				return fmt.Errorf("invalid data type, page: %s, data_type: %s, err: %w", n, p.DataType, err)
+			}
+			site.setDataHandler(n, h)
 		case strings.HasPrefix(fmt.Sprintf("%v", p.Data), filePrefix):
 			f, ok := p.Data.(string)
 			if !ok {
-				log.Panicf("invalid data type, page: %s, data: %v", n, p.Data)
+				return fmt.Errorf("invalid data type, page: %s, data: %v", n, p.Data)
 			}
-			f = f[len(filePrefix):]
-			site.SetDataHandler(n, site.fileDataHandler(p.Path, f, p.MaxAge))
+			f = strings.TrimPrefix(f, filePrefix)
+			site.setDataHandler(n, site.fileDataHandler(p.Path, f, site.staticMaxAge(p)))
 			pp := site.Pages[n]
 			pp.isStatic = true
+			if ff, err := fs.Stat(site.fs, cleanPath(f)); err == nil {
+				pp.isDir = ff.IsDir()
+			}
 			site.Pages[n] = pp
 		default:
 			// serve it as raw data
-			site.SetDataHandler(n, func(rw http.ResponseWriter, r *http.Request) interface{} {
+			site.setDataHandler(n, func(rw http.ResponseWriter, r *http.Request) interface{} {
 				return p.Data
 			})
 		}
 	}
+	return nil
+}
+
+// setupTimeZone bind the time funcs to the site time zone.
+func (site *Site) setupTimeZone() error {
+	if site.TimeZone == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(site.TimeZone); err != nil {
+		return fmt.Errorf("invalid timezone: %s, err: %w", site.TimeZone, err)
+	}
+	zoneOrDefault := func(zone []string) []string {
+		if len(zone) == 0 || zone[0] == "" {
+			return []string{site.TimeZone}
+		}
+		return zone
+	}
+	site.funcs["year"] = func(zone ...string) (int, error) { return funcs.Year(zoneOrDefault(zone)...) }
+	site.funcs["today"] = func(zone ...string) (string, error) { return funcs.Today(zoneOrDefault(zone)...) }
+	return nil
+}
+
+func (site *Site) setupRedirects() error {
+	if site.Redirects == "" {
+		return nil
+	}
+	redirects, err := loadRedirects(site.fs, strings.TrimPrefix(site.Redirects, filePrefix))
+	if err != nil {
+		return fmt.Errorf("invalid redirects: %s, err: %w", site.Redirects, err)
+	}
+	site.redirects = redirects
+	return nil
 }
 
 func (site *Site) setupRouter() {
 	router := mux.NewRouter()
+	// assets are registered first so that they take priority over the pages.
+	for _, a := range site.assets {
+		site.logger.Infof("register assets: %s, path: %s", a.dir, a.prefix)
+		router.PathPrefix(a.prefix).Methods(http.MethodGet, http.MethodHead).Handler(site.assetHandler(a))
+	}
+	if site.loginFunc != nil {
+		site.logger.Infof("register login: %s, method: %s", site.Login, http.MethodPost)
+		var h http.Handler = site.loginHandler()
+		// the rate limit of the login page also protects the login form from brute force.
+		if p, ok := site.Pages[site.loginPage()]; ok && p.RateLimit != nil {
+			h = RateLimit(p.RateLimit.RPS, p.RateLimit.Burst)(h)
+		}
+		router.Path(site.Login).Methods(http.MethodPost).Handler(h)
+	}
 	for name, p := range site.Pages {
-		log.Printf("info: register page: %s, path: %s, method: %s\n", name, p.Path, http.MethodGet)
+		// variants without path are served only at the path of their pages.
+		if p.Path == "" && site.isVariant(name) {
+			continue
+		}
+		methods := p.methods()
+		site.logger.Infof("register page: %s, path: %s, method: %s", name, p.Path, strings.Join(methods, ","))
 		h := site.getPageHandler(name)
-		if p.Auth {
-			h = AuthRequired(site.Login, site.authInfo)(h)
+		if len(p.Variants) > 0 {
+			h = site.getVariantHandler(name, p.Variants)
 		}
+		h = site.pageMiddlewares(p)(h)
 		if p.isStaticDir() {
-			router.PathPrefix(p.Path).Methods(http.MethodGet).Handler(h)
+			router.PathPrefix(p.Path).Methods(methods...).Handler(h)
 		} else {
-			router.Path(p.Path).Methods(http.MethodGet).Handler(h)
+			router.Path(p.Path).Methods(methods...).Handler(h)
+		}
+		if name == site.Home && p.Path != "/" {
+			site.logger.Infof("register home page: %s, path: /, method: %s", name, strings.Join(methods, ","))
+			router.Path("/").Methods(methods...).Handler(h)
 		}
 	}
 	router.NotFoundHandler = site.getPageHandler(PageNotFound)
 	site.router = router
+	var h http.Handler = router
+	if len(site.redirects) > 0 {
+		h = Redirects(site.redirects)(h)
+	}
+	// apply global middlewares, the first one is the outermost.
+	for i := len(site.middlewares) - 1; i >= 0; i-- {
+		h = site.middlewares[i](h)
+	}
+	// templates are reloaded on every request in development, so are the responses.
+	if site.Reload {
+		h = NoStore()(h)
+	}
+	site.handler = h
+	site.compress = nil
+	if site.Compress {
+		site.compress = Compress(site.compressOpts...)
+	}
+}
+
+// pageMiddlewares return the middleware applies the auth and rate limit of the page.
+func (site *Site) pageMiddlewares(p Page) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		if p.Auth {
+			h = AuthRequired(site.Login, site.authInfo, p.AuthMode)(h)
+		}
+		if p.RateLimit != nil {
+			h = RateLimit(p.RateLimit.RPS, p.RateLimit.Burst)(h)
+		}
+		return h
+	}
 }
 
 // getPageData get common data from configuration and request.
+// The assembled data is cached in the request context, so that the data handler
+// of a page is invoked at most once per request.
 func (site *Site) getPageData(pageName string, rw http.ResponseWriter, r *http.Request) PageData {
+	cache, _ := r.Context().Value(pageDataCacheKey{}).(pageDataCache)
+	if data, ok := cache[pageName]; ok {
+		return data
+	}
 	// get claims information.
 	var claims interface{}
 	authenticated := false
@@ -236,6 +657,23 @@ func (site *Site) getPageData(pageName string, rw http.ResponseWriter, r *http.R
 		User:          claims,
 		Error:         nil,
 		Cookies:       make(map[string]*http.Cookie),
+		Flags:         site.getFlags(),
+		BuildInfo:     site.buildInfo,
+		URL:           cloneURL(r.URL),
+		Query:         r.URL.Query(),
+		Vars:          make(map[string]string),
+		IsBot:         site.isBot(r.UserAgent()),
+		CSPNonce:      cspNonce(r),
+		CSRFToken:     csrfToken(r),
+	}
+	for k, v := range mux.Vars(r) {
+		data.Vars[k] = v
+	}
+	if site.detectBaseURL && data.MetaData.BaseURL() == "" {
+		data.MetaData.SetBaseURL(requestBaseURL(r))
+	}
+	if len(site.Languages) > 0 {
+		data.Alternates = site.getAlternates(r, data.MetaData.BaseURL())
 	}
 	// collect cookies if any.
 	for _, ck := range r.Cookies() {
@@ -257,55 +695,190 @@ func (site *Site) getPageData(pageName string, rw http.ResponseWriter, r *http.R
 		// in case we have predefined data.
 		data.Data = p.Data
 	}
+	// cookies set by the data handler are considered, so that consent takes effect immediately.
+	data.Tracking = site.renderTracking(data.Cookies)
+	if cache != nil {
+		cache[pageName] = data
+	}
 	return data
 }
 
+// withPageDataCache return a request with a page data cache attached to its context.
+func withPageDataCache(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(pageDataCacheKey{}).(pageDataCache); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), pageDataCacheKey{}, pageDataCache{}))
+}
+
 func (site *Site) getPageHandler(name string) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		if _, ok := site.Pages[name]; !ok {
 			http.Error(rw, "Page Not Found", http.StatusNotFound)
 			return
 		}
+		r = withPageDataCache(r)
+		if !site.Pages[name].isStatic {
+			r = withCSPNonce(r)
+			site.setCacheHeader(rw, site.Pages[name])
+		}
+		site.setPageHeaders(rw, r, name)
 		data := site.getPageData(name, rw, r)
 		if data.Error != nil {
-			site.handleError(rw, r, data.Error)
+			site.handleError(rw, r, name, data.Error)
 			return
 		}
 		if site.Pages[name].isStatic {
 			return
 		}
-		data.MetaData.SetCanonicalURL(data.MetaData.BaseURL() + r.URL.Path)
+		if render, ok := builtinRenderers[site.Pages[name].DataType]; ok {
+			if err := render(rw, data.Data); err != nil {
+				site.logger.Errorf("render page: %s, err: %v", name, err)
+				site.handleError(rw, r, name, err)
+			}
+			return
+		}
+		if site.Pages[name].JSON {
+			// the response depends on the Accept header.
+			rw.Header().Add("Vary", "Accept")
+			if prefersJSON(r) {
+				if err := writeJSON(rw, data.Data); err != nil {
+					site.logger.Errorf("render page: %s, err: %v", name, err)
+					site.handleError(rw, r, name, err)
+				}
+				return
+			}
+		}
+		canonicalPath := r.URL.Path
+		if name == site.Home {
+			canonicalPath = "/"
+		}
+		data.MetaData.SetCanonicalURL(absURL(data.MetaData.BaseURL(), canonicalPath))
 		if err := site.handlePage(rw, r, name, data); err != nil {
-			log.Printf("error: template:%s, err: %v\n", name, err)
-			site.handleError(rw, r, err)
+			site.logger.Errorf("template: %s, err: %v", name, err)
+			site.handleError(rw, r, name, err)
 			return
 		}
 	})
 }
 
+// staticMaxAge return the max age of the static page, fallback to the site's static max age and max age.
+func (site *Site) staticMaxAge(p Page) time.Duration {
+	if p.MaxAge > 0 {
+		return p.MaxAge
+	}
+	if site.StaticMaxAge > 0 {
+		return site.StaticMaxAge
+	}
+	return site.MaxAge
+}
+
+// setCacheHeader set the Cache-Control header of the rendered page
+// if its max age or the site's page max age is provided.
+func (site *Site) setCacheHeader(rw http.ResponseWriter, p Page) {
+	maxAge := p.MaxAge
+	if maxAge <= 0 {
+		maxAge = site.PageMaxAge
+	}
+	if maxAge <= 0 {
+		return
+	}
+	scope := "public"
+	if p.Auth {
+		scope = "private"
+	}
+	rw.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int64(maxAge.Seconds())))
+}
+
+// setPageHeaders set the configured headers of the page on the response.
+// The nonce placeholder of the CSP headers is replaced by the nonce of the request, see CSPNoncePlaceholder.
+func (site *Site) setPageHeaders(rw http.ResponseWriter, r *http.Request, name string) {
+	p := site.Pages[name]
+	addLinkHeaders(rw.Header(), "preconnect", site.Preconnect...)
+	addLinkHeaders(rw.Header(), "preconnect", p.Preconnect...)
+	addLinkHeaders(rw.Header(), "dns-prefetch", site.DNSPrefetch...)
+	addLinkHeaders(rw.Header(), "dns-prefetch", p.DNSPrefetch...)
+	setHeaders(rw.Header(), site.Headers)
+	setHeaders(rw.Header(), p.Headers)
+	setCSPNonce(rw.Header(), r)
+}
+
+// setHeaders set the headers, Link headers are added instead since a response can have many of them.
+func setHeaders(h http.Header, headers map[string]string) {
+	for k, v := range headers {
+		if http.CanonicalHeaderKey(k) != "Link" {
+			h.Set(k, v)
+			continue
+		}
+		exists := false
+		for _, l := range h.Values("Link") {
+			exists = exists || l == v
+		}
+		if !exists {
+			h.Add("Link", v)
+		}
+	}
+}
+
+// addLinkHeaders add Link headers with the given rel for the urls, existing links are not duplicated.
+func addLinkHeaders(h http.Header, rel string, urls ...string) {
+	existing := make(map[string]bool)
+	for _, v := range h.Values("Link") {
+		existing[v] = true
+	}
+	for _, u := range urls {
+		link := fmt.Sprintf("<%s>; rel=%s", u, rel)
+		if existing[link] {
+			continue
+		}
+		existing[link] = true
+		h.Add("Link", link)
+	}
+}
+
 func (site *Site) fileDataHandler(prefix string, f string, maxAge time.Duration) DataHandler {
 	return func(rw http.ResponseWriter, r *http.Request) interface{} {
-		ff, err := os.Stat(f)
+		ff, err := fs.Stat(site.fs, cleanPath(f))
 		if err != nil {
 			return err
 		}
 		if ff.IsDir() {
-			h := Cache(maxAge)(http.StripPrefix(prefix, http.FileServer(http.Dir(f))))
+			dir, err := httpDir(site.fs, f)
+			if err != nil {
+				return err
+			}
+			h := Cache(maxAge)(http.StripPrefix(prefix, http.FileServer(dir)))
 			h.ServeHTTP(rw, r)
 			return nil
 		}
-		http.ServeFile(rw, r, f)
+		h := Cache(maxAge)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if err := serveFile(rw, r, site.fs, f); err != nil {
+				site.logger.Errorf("serve file: %s, err: %v", f, err)
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}))
+		h.ServeHTTP(rw, r)
 		return nil
 	}
 }
 
 // ServeHTTP serve the configured pages.
 func (site *Site) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	if site.StaticSite.Enable {
-		site.staticGeneratorHandler()(site.router).ServeHTTP(rw, r)
-		return
+	site.mu.RLock()
+	h, compress := site.handler, site.compress
+	cur := site
+	if site.next != nil {
+		cur = site.next
 	}
-	site.router.ServeHTTP(rw, r)
+	site.mu.RUnlock()
+	if cur.StaticSite.Enable {
+		h = cur.staticGeneratorHandler()(h)
+	}
+	// compress outside of the static generator, so that the generated files are not compressed.
+	if compress != nil {
+		h = compress(h)
+	}
+	h.ServeHTTP(rw, r)
 }
 
 func (site *Site) SetDataHandlers(handlers map[string]DataHandler) error {
@@ -320,6 +893,23 @@ func (site *Site) SetDataHandlers(handlers map[string]DataHandler) error {
 func (site *Site) SetDataHandler(name string, h DataHandler) error {
 	site.mu.Lock()
 	defer site.mu.Unlock()
+	if site.next != nil {
+		// the site was reloaded, the new config serves the requests.
+		if err := site.next.SetDataHandler(name, h); err != nil {
+			return err
+		}
+	} else if err := site.setDataHandler(name, h); err != nil {
+		return err
+	}
+	if site.dataHandlers == nil {
+		site.dataHandlers = make(map[string]DataHandler)
+	}
+	site.dataHandlers[name] = h
+	return nil
+}
+
+// setDataHandler set data handler of the page without keeping it for the reloads.
+func (site *Site) setDataHandler(name string, h DataHandler) error {
 	p, ok := site.Pages[name]
 	if !ok {
 		return NewError(http.StatusNotFound, "page not found")
@@ -342,21 +932,17 @@ func (site *Site) SetRobotsTXTDataHandler(name string, h RobotsTXTDataHandler) e
 }
 
 // getPageMetaData get metadata from config.
+// A new map is returned, so that it can be modified per request.
 func (site *Site) getPageMetaData(name string) MetaData {
-	page, ok := site.Pages[name]
-	if !ok {
-		return site.MetaData
-	}
-	if page.MetaData == nil {
-		page.MetaData = make(MetaData)
-	}
+	md := make(MetaData)
 	// get value from site if page doesn't defined.
 	for k, v := range site.MetaData {
-		if _, ok := page.MetaData[k]; !ok {
-			page.MetaData[k] = v
-		}
+		md[k] = v
+	}
+	for k, v := range site.Pages[name].MetaData {
+		md[k] = v
 	}
-	return page.MetaData
+	return md
 }
 
 // parseTemplate parse the template base on the given config name.
@@ -384,53 +970,406 @@ func (site *Site) parseTemplate(name string) (*template.Template, error) {
 		tplName = fmt.Sprintf("%s.html", tplName)
 	}
 	// load predefined template with default delims.
-	tpl = template.New(tplName).Delims(DefaultDelimLeft, DefaultDelimRight).Funcs(site.funcs)
+	tpl = template.New(tplName).Delims(DefaultDelimLeft, DefaultDelimRight).Funcs(site.getPageFuncs(page))
 	// delims can be overridden page by page.
 	delimLeft, delimRight := page.DelimLeft, page.DelimRight
 	if delimLeft == "" || delimRight == "" {
 		delimLeft, delimRight = site.DelimLeft, site.DelimRight
 	}
-	tpl, err := tpl.Delims(delimLeft, delimRight).ParseFiles(files...)
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, cleanPath(f))
+	}
+	tpl, err := tpl.Delims(delimLeft, delimRight).ParseFS(site.fs, paths...)
 	if err != nil {
-		log.Printf("error: parse template, err: %v\n", err)
+		site.logger.Errorf("parse template: %s, err: %v", name, err)
 		return nil, err
 	}
 	site.templates[name] = tpl
 	return tpl, nil
 }
 
-func (site *Site) handleError(rw http.ResponseWriter, r *http.Request, err error) {
+// renderTemplateFunc return a func which parses and executes a template string with the site funcs,
+// restricted to the allowed funcs of the calling page if provided, see Page.AllowedFuncs.
+// The depth is the level of nested render_template calls, which is limited to prevent runaway recursion.
+func (site *Site) renderTemplateFunc(allowedFuncs []string, depth int) func(src string, data interface{}) (template.HTML, error) {
+	return func(src string, data interface{}) (template.HTML, error) {
+		if max := site.maxRenderDepth(); depth >= max {
+			return "", fmt.Errorf("render_template: exceeded max depth %d", max)
+		}
+		if len(src) > maxRenderTemplateSize {
+			return "", fmt.Errorf("render_template: template size %d exceeded max size %d", len(src), maxRenderTemplateSize)
+		}
+		pageFuncs := site.getPageFuncs(Page{AllowedFuncs: allowedFuncs})
+		funcs := make(map[string]interface{}, len(pageFuncs))
+		for k, v := range pageFuncs {
+			funcs[k] = v
+		}
+		if _, ok := funcs["render_template"]; ok {
+			funcs["render_template"] = site.renderTemplateFunc(allowedFuncs, depth+1)
+		}
+		tpl, err := template.New("render_template").Delims(site.DelimLeft, site.DelimRight).Funcs(funcs).Parse(src)
+		if err != nil {
+			return "", err
+		}
+		buf := &bytes.Buffer{}
+		if err := tpl.Execute(buf, data); err != nil {
+			return "", err
+		}
+		if buf.Len() > maxRenderTemplateSize {
+			return "", fmt.Errorf("render_template: output size %d exceeded max size %d", buf.Len(), maxRenderTemplateSize)
+		}
+		return template.HTML(buf.String()), nil
+	}
+}
+
+// getPageFuncs return the funcs available to the page templates.
+// If the page has allowed funcs, only the allowed ones are returned.
+func (site *Site) getPageFuncs(page Page) map[string]interface{} {
+	if page.AllowedFuncs == nil {
+		return site.funcs
+	}
+	funcs := make(map[string]interface{})
+	for _, name := range page.AllowedFuncs {
+		if f, ok := site.funcs[name]; ok {
+			funcs[name] = f
+		}
+	}
+	// the nested templates are restricted to the same funcs.
+	if _, ok := funcs["render_template"]; ok {
+		funcs["render_template"] = site.renderTemplateFunc(page.AllowedFuncs, 0)
+	}
+	return funcs
+}
+
+// maxRenderDepth return the max nesting depth of render_template.
+func (site *Site) maxRenderDepth() int {
+	if site.MaxRenderDepth > 0 {
+		return site.MaxRenderDepth
+	}
+	return maxRenderTemplateDepth
+}
+
+// handleError write the error page mapped from the error, or the error as JSON
+// if the client prefers JSON or the page of the request is a JSON page, see isJSONPage.
+func (site *Site) handleError(rw http.ResponseWriter, r *http.Request, page string, err error) {
+	if prefersJSON(r) || site.isJSONPage(page, r) {
+		site.handleJSONError(rw, err)
+		return
+	}
 	name := PageError
-	if t, ok := site.errors[ErrorFromErr(err).Code()]; ok {
+	code := ErrorFromErr(err).Code()
+	if t, ok := site.errors[code]; ok {
 		name = t
 	}
+	if code < 100 || code > 599 {
+		code = http.StatusInternalServerError
+	}
 	if _, ok := site.Pages[name]; !ok {
 		http.Error(rw, "Page Not Found", http.StatusNotFound)
 		return
 	}
+	// errors must not be cached as the page.
+	rw.Header().Del("Cache-Control")
+	r = withCSPNonce(r)
+	site.setPageHeaders(rw, r, name)
 	data := site.getPageData(name, rw, r)
 	data.Error = err
-	if err := site.handlePage(rw, r, name, data); err != nil {
-		log.Printf("error: serve error page, err: %v\n", err)
+	// the status is written lazily, so that the fallback error below can still be written if the page fails.
+	if err := site.handlePage(&statusResponseWriter{ResponseWriter: rw, status: code}, r, name, data); err != nil {
+		site.logger.Errorf("serve error page, err: %v", err)
 		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 	}
 }
 
+// isJSONPage report whether the errors of the page should be written as JSON:
+// pages of JSON data type, and pages served as JSON unless the client asks for HTML explicitly.
+func (site *Site) isJSONPage(name string, r *http.Request) bool {
+	p, ok := site.Pages[name]
+	if !ok {
+		return false
+	}
+	return p.DataType == DataTypeJSON || (p.JSON && !acceptsHTML(r))
+}
+
+// handleJSONError write the error as a JSON object with the status mapped from the error.
+func (site *Site) handleJSONError(rw http.ResponseWriter, err error) {
+	e := ErrorFromErr(err)
+	code := e.Code()
+	if code < 100 || code > 599 {
+		code = http.StatusInternalServerError
+	}
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(code)
+	if err := json.NewEncoder(rw).Encode(map[string]interface{}{
+		"error": e.Error(),
+		"code":  code,
+	}); err != nil {
+		site.logger.Errorf("write json error, err: %v", err)
+	}
+}
+
 func (site *Site) handlePage(w http.ResponseWriter, r *http.Request, name string, data interface{}) error {
 	t, err := site.parseTemplate(name)
 	if err != nil {
-		log.Printf("error: %s parse failed, err: %v\n", name, err)
+		site.logger.Errorf("%s parse failed, err: %v", name, err)
 		return err
 	}
 	if data == nil {
 		data = site.getPageData(name, w, r)
 	}
-	if err := t.Execute(w, data); err != nil {
+	// base URL is derived from the request, bind abs_url to it.
+	if pd, ok := data.(PageData); ok && site.detectBaseURL && site.MetaData.BaseURL() == "" {
+		if t, err = t.Clone(); err != nil {
+			return err
+		}
+		base := pd.MetaData.BaseURL()
+		t.Funcs(map[string]interface{}{
+			"abs_url": func(p string) string { return absURL(base, p) },
+		})
+	}
+	if site.isStream(name, data) {
+		return site.streamPage(w, r, name, t, data)
+	}
+	// render into a buffer so that the response can be transformed before written.
+	b, err := site.executeTemplate(r.Context(), t, data)
+	if err != nil {
+		return err
+	}
+	if isHTML(w, b) {
+		for _, transform := range site.transforms {
+			b = transform(b, r)
+		}
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// isStream report whether the page should be streamed, i.e: its data is a channel.
+func (site *Site) isStream(name string, data interface{}) bool {
+	if site.Pages[name].Stream {
+		return true
+	}
+	if pd, ok := data.(PageData); ok {
+		data = pd.Data
+	}
+	return data != nil && reflect.TypeOf(data).Kind() == reflect.Chan
+}
+
+// streamPage render the page directly to the client, the rendered content is flushed
+// once it exceeds streamFlushSize or at the flush calls of the template, i.e: [[flush]].
+// Since the response may be partially written, errors after that are only logged.
+func (site *Site) streamPage(w http.ResponseWriter, r *http.Request, name string, t *template.Template, data interface{}) error {
+	t, err := t.Clone()
+	if err != nil {
 		return err
 	}
+	ctx, cancel := site.renderContext(r.Context())
+	defer cancel()
+	fw := &flushWriter{w: w}
+	cw := &ctxWriter{ctx: ctx, w: fw}
+	t.Funcs(map[string]interface{}{
+		"flush": func() string {
+			cw.Flush()
+			return ""
+		},
+	})
+	if err := site.execute(cw, t, data); err != nil {
+		if !fw.written {
+			return err
+		}
+		site.logger.Errorf("stream page: %s, err: %v", name, err)
+		return nil
+	}
+	cw.Flush()
 	return nil
 }
 
+// executeTemplate render the template into a buffer within the render timeout if any.
+func (site *Site) executeTemplate(ctx context.Context, t *template.Template, data interface{}) ([]byte, error) {
+	ctx, cancel := site.renderContext(ctx)
+	defer cancel()
+	buf := &bytes.Buffer{}
+	if err := site.execute(&ctxWriter{ctx: ctx, w: buf}, t, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderContext return the context of rendering a page, which is done once the render timeout is exceeded if any.
+func (site *Site) renderContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if site.RenderTimeout > 0 {
+		return context.WithTimeout(ctx, site.RenderTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// execute render the template to the writer. If the render timeout is set, the rendering is aborted
+// at its next write once the timeout is exceeded, and an error is returned immediately.
+func (site *Site) execute(cw *ctxWriter, t *template.Template, data interface{}) error {
+	if site.RenderTimeout <= 0 {
+		return t.Execute(cw, data)
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("render panic: %v", r)
+			}
+		}()
+		done <- t.Execute(cw, data)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-cw.ctx.Done():
+		// the rendering may still be running, it must not write after this returns.
+		cw.close()
+		return fmt.Errorf("render: exceeded timeout %v, err: %w", site.RenderTimeout, cw.ctx.Err())
+	}
+}
+
+// ctxWriter writes to the underlying writer until its context is done or it's closed.
+type ctxWriter struct {
+	ctx    context.Context
+	w      io.Writer
+	mu     sync.Mutex
+	closed bool
+}
+
+func (cw *ctxWriter) Write(b []byte) (int, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(b)
+}
+
+// Flush flushes the underlying writer if it's a http.Flusher and the writer is not done.
+func (cw *ctxWriter) Flush() {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if f, ok := cw.w.(http.Flusher); ok && !cw.closed && cw.ctx.Err() == nil {
+		f.Flush()
+	}
+}
+
+func (cw *ctxWriter) close() {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.closed = true
+}
+
+// statusResponseWriter writes the status before the first write of the body.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	// force writes the status even if the handler writes another one.
+	force bool
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.force {
+		code = w.status
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(w.status)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// flushWriter buffers the writes and flushes them to the client once they exceed streamFlushSize.
+type flushWriter struct {
+	w       http.ResponseWriter
+	buf     bytes.Buffer
+	written bool
+	err     error
+}
+
+func (fw *flushWriter) Write(b []byte) (int, error) {
+	if fw.err != nil {
+		return 0, fw.err
+	}
+	n, _ := fw.buf.Write(b)
+	if fw.buf.Len() >= streamFlushSize {
+		fw.Flush()
+	}
+	return n, nil
+}
+
+// Flush write the buffered content to the client and flush it.
+func (fw *flushWriter) Flush() {
+	if fw.buf.Len() > 0 {
+		fw.written = true
+		_, fw.err = fw.w.Write(fw.buf.Bytes())
+		fw.buf.Reset()
+	}
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// cloneURL return a copy of the given URL, so that it can be modified per page.
+func cloneURL(u *url.URL) *url.URL {
+	if u == nil {
+		return &url.URL{}
+	}
+	c := *u
+	if u.User != nil {
+		user := *u.User
+		c.User = &user
+	}
+	return &c
+}
+
+// absURL join the base URL and the path into an absolute URL.
+// The path is returned as is if it's already an absolute URL.
+func absURL(base string, p string) string {
+	if u, err := url.Parse(p); err == nil && (u.IsAbs() || u.Host != "") {
+		return p
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return strings.TrimRight(base, "/") + p
+}
+
+// isHTML report whether the response is a HTML document,
+// base on its Content-Type header or its content if the header is not set.
+func isHTML(w http.ResponseWriter, b []byte) bool {
+	ct := w.Header().Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(b)
+	}
+	return strings.HasPrefix(ct, "text/html")
+}
+
+// QueryGet return the first value of the given query param, or empty if it's not present.
+func (page PageData) QueryGet(k string) string {
+	return page.Query.Get(k)
+}
+
 func (page PageData) GetCookie(k string) string {
 	if ck, ok := page.Cookies[k]; ok {
 		return ck.Value
@@ -439,23 +1378,37 @@ func (page PageData) GetCookie(k string) string {
 }
 
 // jsonFileDataHandler return DataHandler that read data from the given json file.
+// The data is validated against the given schema file if provided.
 // Data can be accessed via .Data in templates.
-// Panics if failed to read the file.
-func (site *Site) jsonFileDataHandler(f string) DataHandler {
+// Return error if failed to read the file or the data is invalid.
+func (site *Site) jsonFileDataHandler(f string, schemaFile string) (DataHandler, error) {
+	var schema *Schema
+	if schemaFile != "" {
+		s, err := loadSchema(site.fs, schemaFile)
+		if err != nil {
+			return nil, err
+		}
+		schema = s
+	}
 	loadData := func() (interface{}, error) {
 		var data interface{}
-		b, err := os.ReadFile(f)
+		b, err := fs.ReadFile(site.fs, cleanPath(f))
 		if err != nil {
 			return nil, NewError(http.StatusInternalServerError, "read data from file, err: %v", err)
 		}
 		if err := json.Unmarshal(b, &data); err != nil {
 			return nil, NewError(http.StatusInternalServerError, "invalid data, err: %v", err)
 		}
+		if schema != nil {
+			if err := schema.Validate(data); err != nil {
+				return nil, NewError(http.StatusInternalServerError, "invalid data: %s, schema: %s, err: %v", f, schemaFile, err)
+			}
+		}
 		return data, nil
 	}
 	data, err := loadData()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	return func(rw http.ResponseWriter, r *http.Request) interface{} {
 		if !site.Reload {
@@ -466,17 +1419,39 @@ func (site *Site) jsonFileDataHandler(f string) DataHandler {
 			return err
 		}
 		return data
-	}
+	}, nil
 }
 
 func (site *Site) validateSite() error {
 	for l, comps := range site.Layouts {
 		for _, c := range comps {
-			if _, err := os.Stat(c); err != nil {
+			if _, err := fs.Stat(site.fs, cleanPath(c)); err != nil {
 				return fmt.Errorf("layout: %s, component: %s, err: %w", l, c, err)
 			}
 		}
 	}
+	// validate home page
+	if site.Home != "" {
+		if _, ok := site.Pages[site.Home]; !ok {
+			return fmt.Errorf("home page: %s not found", site.Home)
+		}
+		for n, p := range site.Pages {
+			if p.Path == "/" && n != site.Home {
+				return fmt.Errorf("home page: %s conflicts with page: %s, path: /", site.Home, n)
+			}
+		}
+	}
+	if err := site.validateLanguages(); err != nil {
+		return err
+	}
+	// validate redirects don't shadow pages
+	for _, rd := range site.redirects {
+		for n, p := range site.Pages {
+			if _, ok := rd.match(p.Path); ok {
+				return fmt.Errorf("redirect: %s conflicts with page: %s, path: %s", rd.From, n, p.Path)
+			}
+		}
+	}
 	auth := false
 	// validate if configured files exists
 	for n, p := range site.Pages {
@@ -486,18 +1461,95 @@ func (site *Site) validateSite() error {
 		}
 		// check if component exists
 		for _, c := range p.Components {
-			if _, err := os.Stat(c); err != nil {
+			if _, err := fs.Stat(site.fs, cleanPath(c)); err != nil {
 				return fmt.Errorf("page: %s, component: %s, err: %w", n, c, err)
 			}
 		}
+		// check if methods are valid
+		for _, m := range p.Methods {
+			if !isValidMethod(m) {
+				return fmt.Errorf("page: %s, unknown method: %s", n, m)
+			}
+		}
+		// check if auth mode is valid
+		switch p.AuthMode {
+		case AuthModeAuto, AuthModeRedirect, AuthModeStatus:
+		default:
+			return fmt.Errorf("page: %s, unknown auth mode: %s", n, p.AuthMode)
+		}
+		if err := site.validateVariants(n, p); err != nil {
+			return err
+		}
+		// check if rate limit is valid
+		if p.RateLimit != nil && (p.RateLimit.RPS <= 0 || p.RateLimit.Burst <= 0) {
+			return fmt.Errorf("page: %s, invalid rate limit: rps and burst must be greater than 0", n)
+		}
 		auth = auth || p.Auth
 	}
 	if auth && site.authInfo == nil {
 		return fmt.Errorf("auth is enabled but no auth info func is provided")
 	}
+	if site.maintenance != "" {
+		if _, ok := site.Pages[site.maintenance]; !ok {
+			return fmt.Errorf("maintenance page: %s not found", site.maintenance)
+		}
+	}
+	if site.loginFunc != nil && site.Login == "" {
+		return fmt.Errorf("login handler is provided but no login path is configured")
+	}
 	return nil
 }
 
+// isBot report whether the user agent matches one of the bot patterns, case-insensitively.
+func (site *Site) isBot(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, p := range site.botPatterns {
+		if p != "" && strings.Contains(ua, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getFlags return a copy of the current feature flags.
+func (site *Site) getFlags() map[string]bool {
+	flags := make(map[string]bool)
+	if site.flags == nil {
+		return flags
+	}
+	for k, v := range site.flags() {
+		flags[k] = v
+	}
+	return flags
+}
+
+// frontMatter read the given file of the site file system and return its front-matter as a map.
+// An empty map is returned if the file has no front-matter.
+// Only the files under the site root can be read, absolute and parent paths are rejected.
+func (site *Site) frontMatter(name string) (map[string]interface{}, error) {
+	name = cleanPath(name)
+	if !fs.ValidPath(name) {
+		return nil, fmt.Errorf("front_matter: invalid path: %s", name)
+	}
+	b, err := fs.ReadFile(site.fs, name)
+	if err != nil {
+		return nil, err
+	}
+	m, _, err := funcs.ParseFrontMatter(b)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// flag report whether the given feature flag is on, unknown flags are off.
+func (site *Site) flag(name string) bool {
+	if site.flags == nil {
+		return false
+	}
+	return site.flags()[name]
+}
+
 // mergeFrom merge the page1 data to the current page data
 func (page *PageData) merge(page1 PageData) {
 	page.Data = page1.Data
@@ -508,20 +1560,73 @@ func (page *PageData) merge(page1 PageData) {
 	for k, ck := range page1.Cookies {
 		page.Cookies[k] = ck
 	}
+	for k, v := range page1.Flags {
+		page.Flags[k] = v
+	}
+	for k, v := range page1.Vars {
+		page.Vars[k] = v
+	}
+	for k, v := range page1.Query {
+		page.Query[k] = v
+	}
+	if page1.Alternates != nil {
+		page.Alternates = page1.Alternates
+	}
 	if page1.User != nil {
 		page.Authenticated = page1.Authenticated
 		page.User = page1.User
 	}
 }
 
-func (p Page) isStaticDir() bool {
-	if !p.isStatic {
-		return false
+func (p Page) hasAnyTag(tags []string) bool {
+	for _, t := range tags {
+		for _, pt := range p.Tags {
+			if t == pt {
+				return true
+			}
+		}
 	}
-	f := p.Data.(string)[len(filePrefix):]
-	ff, err := os.Stat(f)
-	if err != nil {
-		return false
+	return false
+}
+
+// pagePaths return the sorted paths of the pages which can be suggested to users,
+// paths with variables and error pages are excluded.
+func (site *Site) pagePaths() []string {
+	paths := make([]string, 0, len(site.Pages))
+	for name, p := range site.Pages {
+		if p.Path == "" || strings.Contains(p.Path, "{") || p.isStatic {
+			continue
+		}
+		if _, ok := site.Errors[name]; ok {
+			continue
+		}
+		paths = append(paths, p.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// methods return the HTTP methods the page accepts.
+func (p Page) methods() []string {
+	if len(p.Methods) == 0 {
+		return []string{http.MethodGet}
+	}
+	methods := make([]string, 0, len(p.Methods))
+	for _, m := range p.Methods {
+		methods = append(methods, strings.ToUpper(m))
 	}
-	return ff.IsDir()
+	return methods
+}
+
+func isValidMethod(m string) bool {
+	switch strings.ToUpper(m) {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func (p Page) isStaticDir() bool {
+	return p.isStatic && p.isDir
 }
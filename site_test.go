@@ -0,0 +1,1351 @@
+package tiny_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pthethanh/tiny"
+)
+
+// newTestSite write the given files and config into a temporary directory
+// and create a new site from it. The working directory is changed to the
+// temporary directory until the test finishes, so paths in config are relative to it.
+func newTestSite(t *testing.T, config string, files map[string]string, opts ...tiny.Option) *tiny.Site {
+	t.Helper()
+	files["index.yml"] = config
+	writeTestFiles(t, files)
+	return tiny.NewSite("index.yml", opts...)
+}
+
+// writeTestFiles write the given files into a temporary directory and change
+// the working directory to it until the test finishes.
+func writeTestFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// serve send a request to the site and return the recorded response.
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	return rw
+}
+
+func TestResponseTransform(t *testing.T) {
+	snippet := "<!-- analytics -->"
+	site := newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+  about:
+    path: /about
+    components: [about.html]
+  sitemap:
+    path: /sitemap.xml
+    components: [sitemap.xml]
+  api:
+    path: /api
+    components: [api.json]
+`, map[string]string{
+		"index.html":  `<html><body>index</body></html>`,
+		"about.html":  `<!DOCTYPE html><html><body>about</body></html>`,
+		"sitemap.xml": `<?xml version="1.0" encoding="UTF-8"?><urlset></urlset>`,
+		"api.json":    `{"name": "tiny"}`,
+	}, tiny.ResponseTransform(func(b []byte, r *http.Request) []byte {
+		return append(b, []byte(snippet)...)
+	}))
+	cases := []struct {
+		path    string
+		snippet bool
+	}{
+		{path: "/", snippet: true},
+		{path: "/about", snippet: true},
+		{path: "/sitemap.xml", snippet: false},
+		{path: "/api", snippet: false},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			rw := serve(site, httptest.NewRequest(http.MethodGet, c.path, nil))
+			if got := strings.Contains(rw.Body.String(), snippet); got != c.snippet {
+				t.Errorf("got snippet=%v, want snippet=%v, body: %s", got, c.snippet, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestHome(t *testing.T) {
+	config := `
+metadata:
+  base_url: http://localhost
+pages:
+  blog:
+    path: /blog
+    components: [blog.html]
+`
+	files := map[string]string{
+		"blog.html": `blog [[.MetaData.CanonicalURL]]`,
+	}
+	site := newTestSite(t, config, files, tiny.Home("blog"))
+	for _, pth := range []string{"/", "/blog"} {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, pth, nil))
+		if rw.Code != http.StatusOK {
+			t.Errorf("path: %s, got code=%d, want code=%d", pth, rw.Code, http.StatusOK)
+		}
+		if got, want := rw.Body.String(), "blog http://localhost/"; got != want {
+			t.Errorf("path: %s, got body=%s, want body=%s", pth, got, want)
+		}
+	}
+	t.Run("home page not found", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("got no panic, want panic for missing home page")
+			}
+		}()
+		newTestSite(t, config, files, tiny.Home("index"))
+	})
+}
+
+func TestDataHandlerInvokedOnce(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  500:
+    path: /500
+    components: [500.html]
+`, map[string]string{
+		"500.html": `[[index .Data 5]]`,
+	})
+	calls := 0
+	site.SetDataHandler(tiny.PageError, func(rw http.ResponseWriter, r *http.Request) interface{} {
+		calls++
+		return []int{1}
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/500", nil))
+	if rw.Code != http.StatusInternalServerError {
+		t.Errorf("got code=%d, want code=%d", rw.Code, http.StatusInternalServerError)
+	}
+	if calls != 1 {
+		t.Errorf("got calls=%d, want calls=1", calls)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	flags := map[string]bool{"new_header": true}
+	site := newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+`, map[string]string{
+		"index.html": `[[if .Flags.new_header]]new[[else]]old[[end]]|[[if flag "new_footer"]]new[[else]]old[[end]]|[[flag "unknown"]]`,
+	}, tiny.Flags(func() map[string]bool {
+		return flags
+	}))
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got, want := rw.Body.String(), "new|old|false"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+	// flip the flags at runtime.
+	flags = map[string]bool{"new_footer": true}
+	rw = serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got, want := rw.Body.String(), "old|new|false"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestJSONError(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+  404:
+    path: /404
+    components: [404.html]
+`, map[string]string{
+		"index.html": `index`,
+		"404.html":   `not found page`,
+	})
+	site.SetDataHandler("index", func(rw http.ResponseWriter, r *http.Request) interface{} {
+		return tiny.NewError(http.StatusNotFound, "post not found")
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/json")
+	rw := serve(site, r)
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("got code=%d, want code=%d", rw.Code, http.StatusNotFound)
+	}
+	if ct := rw.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("got Content-Type=%s, want application/json", ct)
+	}
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["error"] != "post not found" || body["code"] != float64(http.StatusNotFound) {
+		t.Errorf("got body=%v, want error and code", body)
+	}
+
+	// browsers still get the HTML error page.
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	rw = serve(site, r)
+	if got, want := rw.Body.String(), "not found page"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestJSONPageError(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  api:
+    path: /api/posts
+    data_type: json
+    data: file://posts.json
+  posts:
+    path: /posts
+    components: [posts.html]
+    json: true
+  404:
+    path: /404
+    components: [404.html]
+`, map[string]string{
+		"posts.json": `[]`,
+		"posts.html": `posts`,
+		"404.html":   `not found page`,
+	})
+	h := func(rw http.ResponseWriter, r *http.Request) interface{} {
+		return tiny.NewError(http.StatusNotFound, "post not found")
+	}
+	site.SetDataHandler("api", h)
+	site.SetDataHandler("posts", h)
+	cases := []struct {
+		path   string
+		accept string
+		json   bool
+	}{
+		{path: "/api/posts", accept: "*/*", json: true},
+		{path: "/api/posts", accept: "text/html", json: true},
+		{path: "/posts", accept: "*/*", json: true},
+		{path: "/posts", accept: "", json: true},
+		{path: "/posts", accept: "text/html,*/*;q=0.8", json: false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, c.path, nil)
+		r.Header.Set("Accept", c.accept)
+		rw := serve(site, r)
+		if rw.Code != http.StatusNotFound {
+			t.Errorf("path: %s, accept: %s, got code=%d, want code=%d", c.path, c.accept, rw.Code, http.StatusNotFound)
+		}
+		isJSON := strings.HasPrefix(rw.Header().Get("Content-Type"), "application/json")
+		if isJSON != c.json {
+			t.Errorf("path: %s, accept: %s, got body=%s, want JSON=%v", c.path, c.accept, rw.Body.String(), c.json)
+		}
+	}
+}
+
+func TestPageJSON(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  posts:
+    path: /posts
+    components: [posts.html]
+    json: true
+    data:
+      posts: [hello, world]
+  about:
+    path: /about
+    components: [posts.html]
+    data:
+      posts: [about]
+`, map[string]string{
+		"posts.html": `[[range .Data.posts]]<li>[[.]]</li>[[end]]`,
+	})
+	request := func(pth, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, pth, nil)
+		r.Header.Set("Accept", accept)
+		return serve(site, r)
+	}
+	// JSON is served from the same page config if the client prefers it.
+	rw := request("/posts", "application/json")
+	if ct := rw.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("got Content-Type=%s, want application/json", ct)
+	}
+	body := map[string][]string{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &body); err != nil {
+		t.Fatalf("got body=%s, err=%v, want JSON body", rw.Body.String(), err)
+	}
+	if got := strings.Join(body["posts"], ","); got != "hello,world" {
+		t.Errorf("got posts=%s, want posts=hello,world", got)
+	}
+	// the template is rendered for the HTML requests.
+	rw = request("/posts", "text/html,application/xhtml+xml,*/*;q=0.8")
+	if got, want := rw.Body.String(), "<li>hello</li><li>world</li>"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+	if got := rw.Header().Get("Vary"); !strings.Contains(got, "Accept") {
+		t.Errorf("got Vary=%s, want Vary contains Accept", got)
+	}
+	// pages without the json flag always render the template.
+	rw = request("/about", "application/json")
+	if got, want := rw.Body.String(), "<li>about</li>"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestPreconnect(t *testing.T) {
+	site := newTestSite(t, `
+preconnect: [https://fonts.gstatic.com]
+pages:
+  index:
+    path: /
+    components: [index.html]
+    preconnect: [https://cdn.example.com, https://fonts.gstatic.com]
+    dns_prefetch: [https://analytics.example.com]
+  about:
+    path: /about
+    components: [index.html]
+`, map[string]string{
+		"index.html": `index`,
+	})
+	cases := []struct {
+		path  string
+		links []string
+	}{
+		{
+			path: "/",
+			links: []string{
+				"<https://fonts.gstatic.com>; rel=preconnect",
+				"<https://cdn.example.com>; rel=preconnect",
+				"<https://analytics.example.com>; rel=dns-prefetch",
+			},
+		},
+		{
+			path: "/about",
+			links: []string{
+				"<https://fonts.gstatic.com>; rel=preconnect",
+			},
+		},
+	}
+	for _, c := range cases {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if got := rw.Header().Values("Link"); strings.Join(got, ",") != strings.Join(c.links, ",") {
+			t.Errorf("path: %s, got links=%v, want links=%v", c.path, got, c.links)
+		}
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+`, map[string]string{
+		"index.html": `[[.BuildInfo.Version]] [[.BuildInfo.Commit]] [[(build_info).BuildTime]]`,
+	}, tiny.BuildInfo("v1.2.3", "abc1234", "2021-05-01T00:00:00Z"))
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got, want := rw.Body.String(), "v1.2.3 abc1234 2021-05-01T00:00:00Z"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestPageTags(t *testing.T) {
+	config := `
+pages:
+  index:
+    path: /
+    components: [index.html]
+    tags: [blog, shop]
+  blog:
+    path: /blog
+    components: [index.html]
+    tags: [blog]
+  shop:
+    path: /shop
+    components: [index.html]
+    tags: [shop]
+  404:
+    path: /404
+    components: [404.html]
+    tags: [%s]
+`
+	files := map[string]string{
+		"index.html": `page`,
+		"404.html":   `not found`,
+	}
+	cases := []struct {
+		name    string
+		opts    []tiny.Option
+		errTags string
+		routed  map[string]bool
+	}{
+		{
+			name:    "include",
+			opts:    []tiny.Option{tiny.IncludeTags("blog")},
+			errTags: "blog",
+			routed:  map[string]bool{"/": true, "/blog": true, "/shop": false},
+		},
+		{
+			name:    "exclude",
+			opts:    []tiny.Option{tiny.ExcludeTags("blog")},
+			errTags: "shop",
+			routed:  map[string]bool{"/": false, "/blog": false, "/shop": true},
+		},
+		{
+			name:   "no filter",
+			routed: map[string]bool{"/": true, "/blog": true, "/shop": true},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			site := newTestSite(t, fmt.Sprintf(config, c.errTags), files, c.opts...)
+			for pth, routed := range c.routed {
+				rw := serve(site, httptest.NewRequest(http.MethodGet, pth, nil))
+				if got := rw.Body.String() == "page"; got != routed {
+					t.Errorf("path: %s, got routed=%v, want routed=%v", pth, got, routed)
+				}
+			}
+		})
+	}
+	t.Run("error page filtered out", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("got no panic, want panic for filtered out error page")
+			}
+		}()
+		newTestSite(t, fmt.Sprintf(config, "shop"), files, tiny.IncludeTags("blog"))
+	})
+}
+
+func TestRenderTemplate(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  index:
+    path: /
+    components: [index.html]
+    data:
+      content: "Hello [[.name | upper]]"
+      name: jack
+  loop:
+    path: /loop
+    components: [loop.html]
+    data: "[[render_template . .]]"
+  500:
+    path: /500
+    components: [500.html]
+`, map[string]string{
+		"index.html": `[[render_template .Data.content .Data]]`,
+		"loop.html":  `[[render_template .Data .Data]]`,
+		"500.html":   `error: [[.Error]]`,
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got, want := rw.Body.String(), "Hello JACK"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+	rw = serve(site, httptest.NewRequest(http.MethodGet, "/loop", nil))
+	if got := rw.Body.String(); !strings.Contains(got, "exceeded max depth") {
+		t.Errorf("got body=%s, want max depth error", got)
+	}
+}
+
+func TestRenderLimits(t *testing.T) {
+	site := newTestSite(t, `
+render_timeout: 20ms
+max_render_depth: 2
+pages:
+  slow:
+    path: /slow
+    components: [slow.html]
+  loop:
+    path: /loop
+    components: [loop.html]
+    data: "[[render_template . .]]"
+  fast:
+    path: /fast
+    components: [fast.html]
+  500:
+    path: /500
+    components: [500.html]
+`, map[string]string{
+		"slow.html": `[[range seq 1 100]][[sleep]][[end]]`,
+		"loop.html": `[[render_template .Data .Data]]`,
+		"fast.html": `ok`,
+		"500.html":  `error: [[.Error]]`,
+	}, tiny.Funcs(map[string]interface{}{
+		"sleep": func() string {
+			time.Sleep(10 * time.Millisecond)
+			return "."
+		},
+	}))
+	start := time.Now()
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("got duration=%v, want the rendering aborted quickly", d)
+	}
+	if rw.Code != http.StatusInternalServerError || !strings.Contains(rw.Body.String(), "exceeded timeout") {
+		t.Errorf("got code=%d, body=%s, want timeout error", rw.Code, rw.Body.String())
+	}
+	rw = serve(site, httptest.NewRequest(http.MethodGet, "/loop", nil))
+	if got := rw.Body.String(); !strings.Contains(got, "exceeded max depth 2") {
+		t.Errorf("got body=%s, want max depth error", got)
+	}
+	// the server keeps serving other pages.
+	rw = serve(site, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rw.Code != http.StatusOK || rw.Body.String() != "ok" {
+		t.Errorf("got code=%d, body=%s, want code=200, body=ok", rw.Code, rw.Body.String())
+	}
+}
+
+func TestDetectBaseURL(t *testing.T) {
+	config := `
+metadata:
+  base_url: ""
+pages:
+  index:
+    path: /blog
+    components: [index.html]
+`
+	files := map[string]string{
+		"index.html": `[[.MetaData.BaseURL]]|[[.MetaData.CanonicalURL]]`,
+	}
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://internal:8080/blog", nil)
+		r.Header.Set("X-Forwarded-Host", "preview-123.example.com")
+		r.Header.Set("X-Forwarded-Proto", "https")
+		return r
+	}
+	site := newTestSite(t, config, files, tiny.DetectBaseURL())
+	rw := serve(site, newRequest())
+	if got, want := rw.Body.String(), "https://preview-123.example.com|https://preview-123.example.com/blog"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+	// forwarded headers are ignored by default.
+	site = newTestSite(t, config, files)
+	rw = serve(site, newRequest())
+	if got, want := rw.Body.String(), "|/blog"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestAllowedFuncs(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  allowed:
+    path: /allowed
+    components: [allowed.html]
+    allowed_funcs: [upper]
+  disallowed:
+    path: /disallowed
+    components: [disallowed.html]
+    allowed_funcs: [upper]
+  unrestricted:
+    path: /unrestricted
+    components: [disallowed.html]
+  nested:
+    path: /nested
+    components: [nested.html]
+    allowed_funcs: [upper, render_template]
+  nested_disallowed:
+    path: /nested/disallowed
+    components: [nested_disallowed.html]
+    allowed_funcs: [upper, render_template]
+  500:
+    path: /500
+    components: [500.html]
+`, map[string]string{
+		"allowed.html":           `[[upper "hello"]]`,
+		"disallowed.html":        `[[env "TINY_TEST_SECRET"]]`,
+		"nested.html":            `[[render_template "[[upper \"hello\"]]" .]]`,
+		"nested_disallowed.html": `[[render_template "[[env \"TINY_TEST_SECRET\"]]" .]]`,
+		"500.html":               `error`,
+	})
+	os.Setenv("TINY_TEST_SECRET", "secret")
+	defer os.Unsetenv("TINY_TEST_SECRET")
+	cases := []struct {
+		path string
+		body string
+	}{
+		{path: "/allowed", body: "HELLO"},
+		{path: "/disallowed", body: "error"},
+		{path: "/unrestricted", body: "secret"},
+		{path: "/nested", body: "HELLO"},
+		{path: "/nested/disallowed", body: "error"},
+	}
+	for _, c := range cases {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if got := rw.Body.String(); got != c.body {
+			t.Errorf("path: %s, got body=%s, want body=%s", c.path, got, c.body)
+		}
+	}
+}
+
+func TestAbsURL(t *testing.T) {
+	files := map[string]string{
+		"index.html": `[[abs_url "blog/post"]]|[[abs_url "/about"]]|[[abs_url "https://cdn.example.com/a.png"]]|[[abs_url "//cdn.example.com/b.png"]]`,
+	}
+	cases := []struct {
+		name   string
+		base   string
+		detect bool
+		want   string
+	}{
+		{
+			name: "base without trailing slash",
+			base: "https://example.com",
+			want: "https://example.com/blog/post|https://example.com/about|https://cdn.example.com/a.png|//cdn.example.com/b.png",
+		},
+		{
+			name: "base with trailing slash",
+			base: "https://example.com/",
+			want: "https://example.com/blog/post|https://example.com/about|https://cdn.example.com/a.png|//cdn.example.com/b.png",
+		},
+		{
+			name: "empty base",
+			base: "",
+			want: "/blog/post|/about|https://cdn.example.com/a.png|//cdn.example.com/b.png",
+		},
+		{
+			name:   "request base",
+			base:   "",
+			detect: true,
+			want:   "http://preview.example.com/blog/post|http://preview.example.com/about|https://cdn.example.com/a.png|//cdn.example.com/b.png",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := []tiny.Option{}
+			if c.detect {
+				opts = append(opts, tiny.DetectBaseURL())
+			}
+			site := newTestSite(t, fmt.Sprintf(`
+metadata:
+  base_url: %q
+pages:
+  index:
+    path: /
+    components: [index.html]
+`, c.base), files, opts...)
+			for i := 0; i < 2; i++ {
+				rw := serve(site, httptest.NewRequest(http.MethodGet, "http://preview.example.com/", nil))
+				if got := rw.Body.String(); got != c.want {
+					t.Errorf("got body=%s, want body=%s", got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	config := `
+pages:
+  index:
+    path: /
+    components: [index.html]
+  404:
+    path: /404
+    components: [404.html]
+`
+	files := map[string]string{
+		"index.html": `index [[.Data]]`,
+		"about.html": `about`,
+		"404.html":   `not found`,
+	}
+	site := newTestSite(t, config, files, tiny.ReloadConfigEvery(10*time.Millisecond))
+	defer site.Close()
+	site.SetDataHandler("index", func(rw http.ResponseWriter, r *http.Request) interface{} {
+		return "data"
+	})
+	if got := serve(site, httptest.NewRequest(http.MethodGet, "/about", nil)).Body.String(); got != "not found" {
+		t.Fatalf("got body=%s, want body=not found", got)
+	}
+	writeConfig := func(config string, mod time.Time) {
+		if err := os.WriteFile("index.yml", []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// make sure the modification is detected regardless of the file system time resolution.
+		if err := os.Chtimes("index.yml", mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(config+`
+  about:
+    path: /about
+    components: [about.html]
+`, time.Now().Add(time.Minute))
+	deadline := time.Now().Add(2 * time.Second)
+	for serve(site, httptest.NewRequest(http.MethodGet, "/about", nil)).Body.String() != "about" {
+		if time.Now().After(deadline) {
+			t.Fatalf("new page is not served after config changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// data handlers are kept after reload.
+	if got := serve(site, httptest.NewRequest(http.MethodGet, "/", nil)).Body.String(); got != "index data" {
+		t.Errorf("got body=%s, want body=index data", got)
+	}
+	// invalid config is not applied.
+	writeConfig(config+`
+  about:
+    path: /about
+    components: [missing.html]
+`, time.Now().Add(2*time.Minute))
+	if err := site.ReloadConfig(); err == nil {
+		t.Errorf("got no error, want error for invalid config")
+	}
+	if got := serve(site, httptest.NewRequest(http.MethodGet, "/about", nil)).Body.String(); got != "about" {
+		t.Errorf("got body=%s, want body=about", got)
+	}
+}
+
+func TestReloadConfigSiteMap(t *testing.T) {
+	config := `
+metadata:
+  base_url: https://example.com
+pages:
+  about:
+    path: %s
+    components: [about.html]
+  sitemap:
+    path: /sitemap.xml
+    data_type: sitemap
+static_site:
+  enable: true
+  allowed_pages: ["^/about"]
+  output:
+    root_dir: public
+  request:
+    host: %s
+    paths: [%s]
+`
+	site := newTestSite(t, fmt.Sprintf(config, "/about", "http://localhost", "/about"), map[string]string{
+		"about.html":   `about`,
+		"public/.keep": ``,
+	})
+	srv := httptest.NewServer(site)
+	defer srv.Close()
+	if err := os.WriteFile("index.yml", []byte(fmt.Sprintf(config, "/about-us", srv.URL, "/about-us")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := site.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	sitemap := site.GenerateSiteMap("https://example.com")
+	if len(sitemap.URLSet) != 1 || sitemap.URLSet[0].Loc != "https://example.com/about-us" {
+		t.Errorf("got sitemap=%v, want sitemap of the reloaded config", sitemap.URLSet)
+	}
+	body := serve(site, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)).Body.String()
+	if !strings.Contains(body, "<loc>https://example.com/about-us</loc>") || strings.Contains(body, "/about<") {
+		t.Errorf("got sitemap=%s, want sitemap of the reloaded config", body)
+	}
+	// the static site is generated from the reloaded config.
+	if err := site.GenerateStaticSite(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(filepath.Join("public", "about-us.html")); err != nil || string(b) != "about" {
+		t.Errorf("got page=%s, err=%v, want generated page of the reloaded config", b, err)
+	}
+	if b, err := os.ReadFile(filepath.Join("public", "sitemap.xml")); err != nil || !strings.Contains(string(b), "/about-us</loc>") {
+		t.Errorf("got sitemap=%s, err=%v, want generated sitemap of the reloaded config", b, err)
+	}
+}
+
+func TestPageURL(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  posts:
+    path: /posts
+    components: [posts.html]
+`, map[string]string{
+		"posts.html": `<a href="[[page_url .URL 2]]">next</a>`,
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/posts?tag=go&page=1", nil))
+	if got, want := rw.Body.String(), `<a href="/posts?page=2&amp;tag=go">next</a>`; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestPageMethods(t *testing.T) {
+	config := `
+pages:
+  contact:
+    path: /contact
+    components: [contact.html]
+    methods: [%s]
+`
+	files := map[string]string{
+		"contact.html": `[[if .Data]]thanks [[.Data]][[else]]form[[end]]`,
+	}
+	site := newTestSite(t, fmt.Sprintf(config, "GET, POST"), files)
+	site.SetDataHandler("contact", func(rw http.ResponseWriter, r *http.Request) interface{} {
+		if r.Method == http.MethodPost {
+			return r.FormValue("name")
+		}
+		return nil
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/contact", nil))
+	if got := rw.Body.String(); got != "form" {
+		t.Errorf("got body=%s, want body=form", got)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/contact", strings.NewReader("name=jack"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rw = serve(site, r)
+	if got := rw.Body.String(); got != "thanks jack" {
+		t.Errorf("got body=%s, want body=thanks jack", got)
+	}
+	rw = serve(site, httptest.NewRequest(http.MethodDelete, "/contact", nil))
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got code=%d, want code=%d", rw.Code, http.StatusMethodNotAllowed)
+	}
+	t.Run("unknown method", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("got no panic, want panic for unknown method")
+			}
+		}()
+		newTestSite(t, fmt.Sprintf(config, "FETCH"), files)
+	})
+}
+
+func TestPageVars(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  post:
+    path: /blog/{slug}
+    components: [post.html]
+`, map[string]string{
+		"post.html": `[[.Vars.slug]]|[[.Data]]`,
+	})
+	site.SetDataHandler("post", func(rw http.ResponseWriter, r *http.Request) interface{} {
+		return "post of " + mux.Vars(r)["slug"]
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/blog/hello-world", nil))
+	if got, want := rw.Body.String(), "hello-world|post of hello-world"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestPageQuery(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  posts:
+    path: /posts
+    components: [posts.html]
+`, map[string]string{
+		"posts.html": `[[.QueryGet "page"]]|[[index .Query "tag"]]|[[.QueryGet "sort"]]`,
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/posts?page=2&tag=go&tag=web&sort=desc", nil))
+	if got, want := rw.Body.String(), "2|[go web]|desc"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+	// query returned by the data handler overrides the request one key by key.
+	site.SetDataHandler("posts", func(rw http.ResponseWriter, r *http.Request) interface{} {
+		return tiny.PageData{Query: url.Values{"sort": {"asc"}}}
+	})
+	rw = serve(site, httptest.NewRequest(http.MethodGet, "/posts?page=2&tag=go&tag=web&sort=desc", nil))
+	if got, want := rw.Body.String(), "2|[go web]|asc"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  about:
+    path: /about
+    components: [page.html]
+  blog:
+    path: /blog
+    components: [page.html]
+  post:
+    path: /blog/{slug}
+    components: [page.html]
+  contact:
+    path: /contact
+    components: [page.html]
+  404:
+    path: /404
+    components: [404.html]
+`, map[string]string{
+		"page.html": `page`,
+		"404.html":  `did you mean [[range suggest .URL.Path 2]][[.]] [[end]]`,
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/contcat", nil))
+	if got, want := rw.Body.String(), "did you mean /contact /about "; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+}
+
+func TestPageHeadersConfig(t *testing.T) {
+	site := newTestSite(t, `
+headers:
+  X-Frame-Options: DENY
+  X-Content-Type-Options: nosniff
+pages:
+  index:
+    path: /
+    components: [index.html]
+    headers:
+      X-Frame-Options: SAMEORIGIN
+      Link: </app.css>; rel=preload; as=style
+  broken:
+    path: /broken
+    components: [index.html]
+    headers:
+      Content-Security-Policy: default-src 'self'
+  500:
+    path: /500
+    components: [500.html]
+    headers:
+      Retry-After: "60"
+`, map[string]string{
+		"index.html": `index`,
+		"500.html":   `error`,
+	})
+	site.SetDataHandler("broken", func(rw http.ResponseWriter, r *http.Request) interface{} {
+		return fmt.Errorf("broken")
+	})
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+	want := map[string]string{
+		"X-Frame-Options":        "SAMEORIGIN",
+		"X-Content-Type-Options": "nosniff",
+		"Link":                   "</app.css>; rel=preload; as=style",
+	}
+	for k, v := range want {
+		if got := rw.Header().Get(k); got != v {
+			t.Errorf("header: %s, got value=%s, want value=%s", k, got, v)
+		}
+	}
+	// headers are set on error pages too.
+	rw = serve(site, httptest.NewRequest(http.MethodGet, "/broken", nil))
+	if rw.Body.String() != "error" {
+		t.Fatalf("got body=%s, want the error page", rw.Body.String())
+	}
+	want = map[string]string{
+		"X-Frame-Options":         "DENY",
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "default-src 'self'",
+		"Retry-After":             "60",
+	}
+	for k, v := range want {
+		if got := rw.Header().Get(k); got != v {
+			t.Errorf("error page, header: %s, got value=%s, want value=%s", k, got, v)
+		}
+	}
+}
+
+func TestIsBot(t *testing.T) {
+	config := `
+pages:
+  index:
+    path: /
+    components: [index.html]
+`
+	files := map[string]string{
+		"index.html": `[[if .IsBot]]bot[[else]]human[[end]]`,
+	}
+	get := func(site *tiny.Site, ua string) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("User-Agent", ua)
+		return serve(site, r).Body.String()
+	}
+	site := newTestSite(t, config, files)
+	cases := []struct {
+		ua   string
+		want string
+	}{
+		{ua: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", want: "bot"},
+		{ua: "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", want: "bot"},
+		{ua: "Mozilla/5.0 (compatible; Yahoo! Slurp; http://help.yahoo.com/help/us/ysearch/slurp)", want: "bot"},
+		{ua: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", want: "bot"},
+		{ua: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", want: "human"},
+		{ua: "", want: "human"},
+	}
+	for _, c := range cases {
+		if got := get(site, c.ua); got != c.want {
+			t.Errorf("user agent: %s, got=%s, want=%s", c.ua, got, c.want)
+		}
+	}
+	// patterns can be overridden.
+	site = newTestSite(t, config, files, tiny.BotPatterns("curl"))
+	if got := get(site, "curl/8.0"); got != "bot" {
+		t.Errorf("got=%s, want=bot for custom pattern", got)
+	}
+	if got := get(site, "Googlebot/2.1"); got != "human" {
+		t.Errorf("got=%s, want=human after overriding patterns", got)
+	}
+}
+
+func TestNewSiteFromFile(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "bad yaml",
+			config: "pages: [",
+			err:    "yaml",
+		},
+		{
+			name: "missing layout file",
+			config: `
+layouts:
+  main: [missing.html]
+pages:
+  index:
+    path: /
+    layout: main
+`,
+			err: "layout: main, component: missing.html",
+		},
+		{
+			name: "bad data_type",
+			config: `
+pages:
+  index:
+    path: /
+    components: [index.html]
+    data_type: json
+    data: [1, 2]
+`,
+			err: "invalid data type, page: index",
+		},
+		{
+			name: "missing component",
+			config: `
+pages:
+  index:
+    path: /
+    components: [missing.html]
+`,
+			err: "page: index, component: missing.html",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			writeTestFiles(t, map[string]string{
+				"index.yml":  c.config,
+				"index.html": `index`,
+			})
+			site, err := tiny.NewSiteFromFile("index.yml")
+			if err == nil || site != nil {
+				t.Fatalf("got site=%v, err=%v, want error", site, err)
+			}
+			if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("got err=%v, want err contains=%s", err, c.err)
+			}
+		})
+	}
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := tiny.NewSiteFromFile("not-found.yml"); !os.IsNotExist(err) {
+			t.Errorf("got err=%v, want not exist error", err)
+		}
+	})
+}
+
+func TestNewSiteFromBytes(t *testing.T) {
+	writeTestFiles(t, map[string]string{
+		"index.html": `index`,
+	})
+	site, err := tiny.NewSiteFromBytes([]byte(`
+pages:
+  index:
+    path: /
+    components: [index.html]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := serve(site, httptest.NewRequest(http.MethodGet, "/", nil)).Body.String(); got != "index" {
+		t.Errorf("got body=%s, want body=index", got)
+	}
+	if _, err := tiny.NewSiteFromBytes([]byte("pages: [")); err == nil {
+		t.Errorf("got no error, want error for bad yaml")
+	}
+	if _, err := tiny.NewSiteFromBytes([]byte("pages: {}"), tiny.WatchConfig()); err == nil {
+		t.Errorf("got no error, want error for watching config without config file")
+	}
+}
+
+func TestWithFS(t *testing.T) {
+	// make sure nothing is read from the working directory.
+	writeTestFiles(t, map[string]string{})
+	fsys := fstest.MapFS{
+		"index.yml": {Data: []byte(`
+layouts:
+  main: [web/main.html]
+pages:
+  index:
+    path: /
+    layout: main
+    components: [web/index.html]
+  posts:
+    path: /posts
+    components: [web/posts.html]
+    data: file://data/posts.json
+    data_type: json
+  static:
+    path: /static/
+    data: file://web/static/
+  robots:
+    path: /robots.txt
+    data: file://web/robots.txt
+`)},
+		"web/main.html":     {Data: []byte(`<main>[[template "content" .]]</main>`)},
+		"web/index.html":    {Data: []byte(`[[define "content"]]index[[end]]`)},
+		"web/posts.html":    {Data: []byte(`[[range .Data.posts]][[.]] [[end]]`)},
+		"data/posts.json":   {Data: []byte(`{"posts": ["hello", "world"]}`)},
+		"web/static/app.js": {Data: []byte(`console.log("hello")`)},
+		"web/robots.txt":    {Data: []byte("User-agent: *")},
+	}
+	site, err := tiny.NewSiteFromFile("index.yml", tiny.WithFS(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		path string
+		body string
+	}{
+		{path: "/", body: "<main>index</main>"},
+		{path: "/posts", body: "hello world "},
+		{path: "/static/app.js", body: `console.log("hello")`},
+		{path: "/robots.txt", body: "User-agent: *"},
+	}
+	for _, c := range cases {
+		rw := serve(site, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if rw.Code != http.StatusOK {
+			t.Errorf("path=%s, got code=%d, want code=%d", c.path, rw.Code, http.StatusOK)
+		}
+		if got := rw.Body.String(); got != c.body {
+			t.Errorf("path=%s, got body=%s, want body=%s", c.path, got, c.body)
+		}
+	}
+	if _, err := tiny.NewSiteFromBytes([]byte(`
+pages:
+  index:
+    path: /
+    components: [web/missing.html]
+`), tiny.WithFS(fsys)); err == nil {
+		t.Errorf("got no error, want error for missing component")
+	}
+}
+
+func TestFrontMatterWithFS(t *testing.T) {
+	writeTestFiles(t, map[string]string{})
+	fsys := fstest.MapFS{
+		"index.yml": {Data: []byte(`
+pages:
+  index:
+    path: /
+    components: [web/index.html]
+  absolute:
+    path: /absolute
+    components: [web/invalid.html]
+    data: /etc/hosts
+  parent:
+    path: /parent
+    components: [web/invalid.html]
+    data: ../index.yml
+  missing:
+    path: /missing
+    components: [web/invalid.html]
+    data: posts/missing.md
+`)},
+		"web/index.html":   {Data: []byte(`[[$m := front_matter "posts/hello.md"]][[$m.title]] [[index $m.tags 1]] [[len (front_matter "posts/plain.md")]]`)},
+		"web/invalid.html": {Data: []byte(`[[front_matter .Data]]`)},
+		"posts/hello.md":   {Data: []byte("---\ntitle: Hello\ntags: [go, web]\n---\n# Hello\n")},
+		"posts/plain.md":   {Data: []byte("# Plain\n")},
+	}
+	site, err := tiny.NewSiteFromFile("index.yml", tiny.WithFS(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got, want := rw.Body.String(), "Hello web 0"; rw.Code != http.StatusOK || got != want {
+		t.Errorf("got code=%d, body=%s, want body=%s", rw.Code, got, want)
+	}
+	// files outside of the site file system can't be read.
+	for _, pth := range []string{"/absolute", "/parent", "/missing"} {
+		if rw := serve(site, httptest.NewRequest(http.MethodGet, pth, nil)); rw.Code == http.StatusOK {
+			t.Errorf("path=%s, got code=%d, want error", pth, rw.Code)
+		}
+	}
+}
+
+func TestTimeZone(t *testing.T) {
+	site := newTestSite(t, `
+timezone: Pacific/Kiritimati
+pages:
+  index:
+    path: /
+    components: [index.html]
+`, map[string]string{
+		"index.html": `[[today]] [[year]] [[today "UTC"]]`,
+	})
+	loc, err := time.LoadLocation("Pacific/Kiritimati")
+	if err != nil {
+		t.Skip(err)
+	}
+	before := time.Now()
+	got := serve(site, httptest.NewRequest(http.MethodGet, "/", nil)).Body.String()
+	after := time.Now()
+	matched := false
+	for _, now := range []time.Time{before, after} {
+		want := fmt.Sprintf("%s %d %s", now.In(loc).Format("2006-01-02"), now.In(loc).Year(), now.UTC().Format("2006-01-02"))
+		matched = matched || got == want
+	}
+	if !matched {
+		t.Errorf("got body=%s, want date in the site time zone", got)
+	}
+	if _, err := tiny.NewSiteFromBytes([]byte("timezone: Invalid/Zone")); err == nil {
+		t.Errorf("got no error, want error for invalid timezone")
+	}
+}
+
+// flushRecorder records the body at each flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes []string
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushes = append(r.flushes, r.Body.String())
+	r.ResponseRecorder.Flush()
+}
+
+func TestStreamPage(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  items:
+    path: /items
+    components: [items.html]
+  error:
+    path: /error
+    components: [error.html]
+`, map[string]string{
+		"items.html": `<ul>[[range .Data]]<li>[[.]]</li>[[flush]][[end]]</ul>`,
+		"error.html": `<ul>[[flush]][[range .Data]]<li>[[.Name]]</li>[[end]]</ul>`,
+	})
+	produce := func(rw http.ResponseWriter, r *http.Request) interface{} {
+		ch := make(chan interface{})
+		go func() {
+			defer close(ch)
+			for i := 1; i <= 3; i++ {
+				select {
+				case ch <- fmt.Sprintf("item %d", i):
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}()
+		return (<-chan interface{})(ch)
+	}
+	if err := site.SetDataHandler("items", produce); err != nil {
+		t.Fatal(err)
+	}
+	if err := site.SetDataHandler("error", produce); err != nil {
+		t.Fatal(err)
+	}
+	rw := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	site.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/items", nil))
+	if got, want := rw.Body.String(), "<ul><li>item 1</li><li>item 2</li><li>item 3</li></ul>"; got != want {
+		t.Errorf("got body=%s, want body=%s", got, want)
+	}
+	// each item is flushed at the flush calls.
+	found := false
+	for _, f := range rw.flushes {
+		found = found || f == "<ul><li>item 1</li>"
+	}
+	if !found {
+		t.Errorf("got flushes=%v, want the first item flushed before the others", rw.flushes)
+	}
+
+	// the partially written response is kept if the template fails.
+	rw = &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	// cancel the request so that the producer stops once the template fails.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	site.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/error", nil).WithContext(ctx))
+	if got := rw.Body.String(); got != "<ul>" {
+		t.Errorf("got body=%s, want partial body", got)
+	}
+}
+
+func TestStreamPageLimits(t *testing.T) {
+	site := newTestSite(t, `
+render_timeout: 20ms
+pages:
+  items:
+    path: /items
+    components: [items.html]
+    stream: true
+  slow:
+    path: /slow
+    components: [items.html]
+  500:
+    path: /500
+    components: [500.html]
+`, map[string]string{
+		"items.html": `[[range .Data]][[.]][[end]]`,
+		"500.html":   `error: [[.Error]]`,
+	})
+	site.SetDataHandler("items", func(rw http.ResponseWriter, r *http.Request) interface{} {
+		return []string{strings.Repeat("a", 3000), strings.Repeat("b", 3000), "c"}
+	})
+	site.SetDataHandler("slow", func(rw http.ResponseWriter, r *http.Request) interface{} {
+		ch := make(chan interface{})
+		go func() {
+			defer close(ch)
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}()
+		return (<-chan interface{})(ch)
+	})
+	// the content is flushed in chunks instead of on every write.
+	rw := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	site.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/items", nil))
+	if got, want := len(rw.flushes), 2; got != want {
+		t.Errorf("got %d flushes, want %d flushes", got, want)
+	}
+	if got, want := rw.Body.Len(), 6001; got != want {
+		t.Errorf("got body length=%d, want body length=%d", got, want)
+	}
+	// the render timeout applies to the streamed pages.
+	rw = &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	site.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rw.Code != http.StatusInternalServerError || !strings.Contains(rw.Body.String(), "exceeded timeout") {
+		t.Errorf("got code=%d, body=%s, want timeout error", rw.Code, rw.Body.String())
+	}
+}
@@ -0,0 +1,150 @@
+package tiny
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	siteMapNS          = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	siteMapContentType = "application/xml; charset=utf-8"
+)
+
+type (
+	siteMapXML struct {
+		XMLName xml.Name        `xml:"urlset"`
+		XMLNS   string          `xml:"xmlns,attr"`
+		URLs    []siteMapURLXML `xml:"url"`
+	}
+
+	siteMapURLXML struct {
+		Loc        string `xml:"loc"`
+		LastMod    string `xml:"lastmod,omitempty"`
+		ChangeFreq string `xml:"changefreq,omitempty"`
+		Priority   string `xml:"priority,omitempty"`
+	}
+)
+
+// Marshal return the XML document of the sitemap.
+func (s SiteMap) Marshal() ([]byte, error) {
+	doc := siteMapXML{
+		XMLNS: siteMapNS,
+		URLs:  make([]siteMapURLXML, 0, len(s.URLSet)),
+	}
+	for _, u := range s.URLSet {
+		v := siteMapURLXML{
+			Loc:        u.Loc,
+			ChangeFreq: u.ChangeFreq,
+		}
+		if !u.LastMod.IsZero() {
+			v.LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		if u.Priority > 0 {
+			v.Priority = strconv.FormatFloat(u.Priority, 'f', 1, 64)
+		}
+		doc.URLs = append(doc.URLs, v)
+	}
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// GenerateSiteMap generate the sitemap from the configured pages.
+// Pages which require authentication, have no paths or dynamic paths or are static files are skipped,
+// since they are either not public or not indexable by their paths.
+// The last modification time is the latest modification time of the templates and data file of the page.
+func (site *Site) GenerateSiteMap(baseURL string) SiteMap {
+	if cur := site.current(); cur != site {
+		return cur.GenerateSiteMap(baseURL)
+	}
+	errorPages := make(map[string]bool)
+	for n := range site.Errors {
+		errorPages[n] = true
+	}
+	urls := make([]SiteMapURL, 0)
+	for n, p := range site.Pages {
+		if p.Auth || p.isStatic || errorPages[n] || p.Path == "" || strings.Contains(p.Path, "{") {
+			continue
+		}
+		if _, ok := builtinRenderers[p.DataType]; ok {
+			continue
+		}
+		// pages without templates are not HTML pages.
+		if len(site.Layouts[p.Layout])+len(p.Components) == 0 || !hasMethod(p.methods(), http.MethodGet) {
+			continue
+		}
+		loc := p.Path
+		if n == site.Home {
+			loc = "/"
+		}
+		urls = append(urls, SiteMapURL{
+			Loc:     absURL(baseURL, loc),
+			LastMod: site.pageLastMod(p),
+		})
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].Loc < urls[j].Loc
+	})
+	return SiteMap{URLSet: urls}
+}
+
+// pageLastMod return the latest modification time of the template and data files of the page.
+func (site *Site) pageLastMod(p Page) time.Time {
+	files := append(append([]string{}, site.Layouts[p.Layout]...), p.Components...)
+	if f, ok := p.Data.(string); ok && strings.HasPrefix(f, filePrefix) {
+		files = append(files, strings.TrimPrefix(f, filePrefix))
+	}
+	var lastMod time.Time
+	for _, f := range files {
+		fi, err := fs.Stat(site.fs, cleanPath(f))
+		if err != nil {
+			continue
+		}
+		if fi.ModTime().After(lastMod) {
+			lastMod = fi.ModTime()
+		}
+	}
+	return lastMod
+}
+
+// siteMapDataHandler return a DataHandler which generates the sitemap from the configured pages.
+func (site *Site) siteMapDataHandler(name string) DataHandler {
+	return func(rw http.ResponseWriter, r *http.Request) interface{} {
+		base := site.getPageMetaData(name).BaseURL()
+		if base == "" && site.detectBaseURL {
+			base = requestBaseURL(r)
+		}
+		return site.GenerateSiteMap(base)
+	}
+}
+
+func renderSiteMap(rw http.ResponseWriter, data interface{}) error {
+	s, ok := data.(SiteMap)
+	if !ok {
+		return NewError(http.StatusInternalServerError, "invalid sitemap data: %T", data)
+	}
+	b, err := s.Marshal()
+	if err != nil {
+		return fmt.Errorf("marshal sitemap, err: %w", err)
+	}
+	rw.Header().Set("Content-Type", siteMapContentType)
+	_, err = rw.Write(b)
+	return err
+}
+
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package tiny_test
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestSiteMap(t *testing.T) {
+	site := newTestSite(t, `
+metadata:
+  base_url: https://example.com/
+home: index
+pages:
+  index:
+    path: /index
+    components: [index.html]
+  about:
+    path: /about
+    components: [about.html]
+  posts:
+    path: /posts
+    components: [posts.html]
+    data: file://posts.json
+    data_type: json
+  post:
+    path: /posts/{id}
+    components: [posts.html]
+  account:
+    path: /account
+    components: [index.html]
+    auth: true
+  static:
+    path: /static/
+    data: file://static/
+  sitemap:
+    path: /sitemap.xml
+    data_type: sitemap
+`, map[string]string{
+		"index.html":    `index`,
+		"about.html":    `about`,
+		"posts.html":    `posts`,
+		"posts.json":    `{}`,
+		"static/app.js": `console.log("hello")`,
+	}, tiny.AuthInfo(func(ctx context.Context) (interface{}, bool) {
+		return nil, false
+	}))
+	lastMod := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	if err := os.Chtimes("posts.json", lastMod, lastMod); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes("posts.html", lastMod.Add(-time.Hour), lastMod.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	rw := serve(site, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got code=%d, want code=%d", rw.Code, http.StatusOK)
+	}
+	if got := rw.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/xml") {
+		t.Errorf("got Content-Type=%s, want application/xml", got)
+	}
+	doc := struct {
+		XMLName xml.Name `xml:"urlset"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		URLs    []struct {
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
+		} `xml:"url"`
+	}{}
+	if err := xml.Unmarshal(rw.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("got invalid xml, err: %v, body: %s", err, rw.Body.String())
+	}
+	if doc.XMLNS != "http://www.sitemaps.org/schemas/sitemap/0.9" {
+		t.Errorf("got xmlns=%s, want sitemap namespace", doc.XMLNS)
+	}
+	locs := make([]string, 0)
+	for _, u := range doc.URLs {
+		locs = append(locs, u.Loc)
+		if u.LastMod == "" {
+			t.Errorf("loc=%s, got no lastmod, want lastmod", u.Loc)
+		}
+		if u.Loc == "https://example.com/posts" && u.LastMod != "2021-05-01T10:00:00Z" {
+			t.Errorf("got lastmod=%s, want lastmod of the data file", u.LastMod)
+		}
+	}
+	want := "https://example.com/ https://example.com/about https://example.com/posts"
+	if got := strings.Join(locs, " "); got != want {
+		t.Errorf("got locs=%s, want locs=%s", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package tiny
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultConsentCookie is the default name of the cookie which holds the tracking consent of the visitors.
+	DefaultConsentCookie = "consent"
+)
+
+type (
+	// Tracking declares the analytics/tracking snippets of the site, which are
+	// rendered only if the visitor gave consent via the consent cookie.
+	Tracking struct {
+		// ConsentCookie is the name of the consent cookie, default to DefaultConsentCookie.
+		// The consent is given if the cookie is present and its value is not one of: 0, false, no, denied.
+		ConsentCookie string            `yaml:"consent_cookie"`
+		Snippets      []TrackingSnippet `yaml:"snippets"`
+
+		templates []*template.Template
+	}
+
+	// TrackingSnippet is a tracking snippet, the template is rendered with the snippet as its data,
+	// i.e: <script async src="https://www.googletagmanager.com/gtag/js?id=[[.ID]]"></script>.
+	// The template can also be loaded from a file, i.e: file://web/analytics.html.
+	TrackingSnippet struct {
+		ID       string `yaml:"id"`
+		Template string `yaml:"template"`
+	}
+)
+
+// setupTracking parse the templates of the tracking snippets.
+func (site *Site) setupTracking() error {
+	if site.Tracking.ConsentCookie == "" {
+		site.Tracking.ConsentCookie = DefaultConsentCookie
+	}
+	site.Tracking.templates = make([]*template.Template, 0, len(site.Tracking.Snippets))
+	for i, s := range site.Tracking.Snippets {
+		src := s.Template
+		if strings.HasPrefix(src, filePrefix) {
+			b, err := fs.ReadFile(site.fs, cleanPath(strings.TrimPrefix(src, filePrefix)))
+			if err != nil {
+				return fmt.Errorf("tracking snippet: %d, err: %w", i, err)
+			}
+			src = string(b)
+		}
+		tpl, err := template.New(fmt.Sprintf("tracking_%d", i)).Delims(site.DelimLeft, site.DelimRight).Parse(src)
+		if err != nil {
+			return fmt.Errorf("tracking snippet: %d, err: %w", i, err)
+		}
+		site.Tracking.templates = append(site.Tracking.templates, tpl)
+	}
+	return nil
+}
+
+// hasTrackingConsent report whether the visitor gave consent for tracking.
+func (site *Site) hasTrackingConsent(cookies map[string]*http.Cookie) bool {
+	ck, ok := cookies[site.Tracking.ConsentCookie]
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(ck.Value)) {
+	case "0", "false", "no", "denied":
+		return false
+	}
+	return true
+}
+
+// renderTracking return the rendered tracking snippets if the visitor gave consent.
+// Snippets failed to render are logged and skipped.
+func (site *Site) renderTracking(cookies map[string]*http.Cookie) template.HTML {
+	if len(site.Tracking.templates) == 0 || !site.hasTrackingConsent(cookies) {
+		return ""
+	}
+	buf := &bytes.Buffer{}
+	for i, tpl := range site.Tracking.templates {
+		b := &bytes.Buffer{}
+		if err := tpl.Execute(b, site.Tracking.Snippets[i]); err != nil {
+			site.logger.Errorf("render tracking snippet: %s, err: %v", site.Tracking.Snippets[i].ID, err)
+			continue
+		}
+		buf.Write(b.Bytes())
+	}
+	return template.HTML(buf.String())
+}
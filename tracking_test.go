@@ -0,0 +1,48 @@
+package tiny_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracking(t *testing.T) {
+	site := newTestSite(t, `
+tracking:
+  consent_cookie: cookie_consent
+  snippets:
+    - id: G-123
+      template: <script src="https://example.com/gtag.js?id=[[.ID]]"></script>
+    - id: pixel
+      template: file://pixel.html
+pages:
+  home:
+    path: /
+    components: [home.html]
+`, map[string]string{
+		"home.html":  `[[.Tracking]]`,
+		"pixel.html": `<img src="/px?id=[[.ID]]">`,
+	})
+	want := `<script src="https://example.com/gtag.js?id=G-123"></script><img src="/px?id=pixel">`
+	cases := []struct {
+		name   string
+		value  string
+		output string
+	}{
+		{name: "consent", value: "yes", output: want},
+		{name: "denied", value: "false", output: ""},
+		{name: "no cookie", output: ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c.value != "" {
+				r.AddCookie(&http.Cookie{Name: "cookie_consent", Value: c.value})
+			}
+			rw := serve(site, r)
+			if got := rw.Body.String(); got != c.output {
+				t.Errorf("got body=%s, want body=%s", got, c.output)
+			}
+		})
+	}
+}
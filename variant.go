@@ -0,0 +1,105 @@
+package tiny
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type (
+	// PageVariant is a variant of a page, which is served instead of the page at the path of the page
+	// if the header or cookie of the request matches, i.e: for A/B testing by a feature bucket cookie.
+	PageVariant struct {
+		// Page is name of the variant page, its path can be omitted since it's served at the path of the page.
+		Page   string `yaml:"page"`
+		Header string `yaml:"header"`
+		Cookie string `yaml:"cookie"`
+		// Value is the value of the header or cookie to match, any non-empty value matches if omitted.
+		Value string `yaml:"value"`
+	}
+)
+
+// match report whether the request matches the variant.
+func (v PageVariant) match(r *http.Request) bool {
+	val := ""
+	if v.Header != "" {
+		val = r.Header.Get(v.Header)
+	} else if ck, err := r.Cookie(v.Cookie); err == nil {
+		val = ck.Value
+	}
+	if v.Value == "" {
+		return val != ""
+	}
+	return val == v.Value
+}
+
+// getVariantHandler return the handler of the page which dispatches the requests to the first matched variant,
+// the page itself is served if none of the variants matches.
+func (site *Site) getVariantHandler(name string, variants []PageVariant) http.Handler {
+	def := site.getPageHandler(name)
+	handlers := make([]http.Handler, 0, len(variants))
+	for _, v := range variants {
+		// the variants are protected by their own auth, rate limit and methods, as their pages are.
+		p := site.Pages[v.Page]
+		h := site.pageMiddlewares(p)(site.getPageHandler(v.Page))
+		handlers = append(handlers, allowMethods(p.methods(), h))
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		// the response depends on the headers and cookies of the variants.
+		for _, v := range variants {
+			if v.Header != "" {
+				rw.Header().Add("Vary", v.Header)
+			} else {
+				rw.Header().Add("Vary", "Cookie")
+			}
+		}
+		for i, v := range variants {
+			if v.match(r) {
+				handlers[i].ServeHTTP(rw, r)
+				return
+			}
+		}
+		def.ServeHTTP(rw, r)
+	})
+}
+
+// isVariant report whether the page is a variant of another page.
+func (site *Site) isVariant(name string) bool {
+	for _, p := range site.Pages {
+		for _, v := range p.Variants {
+			if v.Page == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateVariants validate the variants of the page refer to the existing pages and have a selection rule.
+func (site *Site) validateVariants(name string, p Page) error {
+	for i, v := range p.Variants {
+		vp, ok := site.Pages[v.Page]
+		if !ok || v.Page == name {
+			return fmt.Errorf("page: %s, variant: %d, invalid page: %s", name, i, v.Page)
+		}
+		if len(vp.Variants) > 0 {
+			return fmt.Errorf("page: %s, variant: %d, page: %s must not have variants", name, i, v.Page)
+		}
+		if (v.Header == "") == (v.Cookie == "") {
+			return fmt.Errorf("page: %s, variant: %d, either header or cookie must be provided", name, i)
+		}
+	}
+	return nil
+}
+
+// allowMethods reject the requests whose methods are not one of the given methods.
+func allowMethods(methods []string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !hasMethod(methods, r.Method) {
+			rw.Header().Set("Allow", strings.Join(methods, ", "))
+			http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		h.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,129 @@
+package tiny_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestPageVariants(t *testing.T) {
+	site := newTestSite(t, `
+pages:
+  pricing:
+    path: /pricing
+    components: [pricing.html]
+    variants:
+      - page: pricing_b
+        cookie: bucket
+        value: b
+      - page: pricing_beta
+        header: X-Beta
+  pricing_b:
+    components: [pricing_b.html]
+  pricing_beta:
+    path: /pricing/beta
+    components: [pricing_beta.html]
+`, map[string]string{
+		"pricing.html":      `pricing a`,
+		"pricing_b.html":    `pricing b`,
+		"pricing_beta.html": `pricing beta`,
+	})
+	cases := []struct {
+		name   string
+		cookie string
+		header string
+		body   string
+	}{
+		{name: "default", body: "pricing a"},
+		{name: "cookie not matched", cookie: "a", body: "pricing a"},
+		{name: "cookie matched", cookie: "b", body: "pricing b"},
+		{name: "header matched", header: "1", body: "pricing beta"},
+		{name: "first matched wins", cookie: "b", header: "1", body: "pricing b"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/pricing", nil)
+			if c.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "bucket", Value: c.cookie})
+			}
+			if c.header != "" {
+				r.Header.Set("X-Beta", c.header)
+			}
+			rw := serve(site, r)
+			if rw.Code != http.StatusOK || rw.Body.String() != c.body {
+				t.Errorf("got status=%d, body=%s, want status=200, body=%s", rw.Code, rw.Body.String(), c.body)
+			}
+			if got := rw.Header().Values("Vary"); len(got) != 2 || got[0] != "Cookie" || got[1] != "X-Beta" {
+				t.Errorf("got Vary=%v, want Vary=[Cookie X-Beta]", got)
+			}
+		})
+	}
+	// variants with path are also served at their own paths.
+	if rw := serve(site, httptest.NewRequest(http.MethodGet, "/pricing/beta", nil)); rw.Body.String() != "pricing beta" {
+		t.Errorf("got body=%s, want body=pricing beta", rw.Body.String())
+	}
+}
+
+func TestPageVariantsAuth(t *testing.T) {
+	site := newTestSite(t, `
+login: /login
+pages:
+  dashboard:
+    path: /dashboard
+    components: [dashboard.html]
+    variants:
+      - page: dashboard_beta
+        cookie: bucket
+        value: beta
+      - page: dashboard_post
+        header: X-Post
+  dashboard_beta:
+    components: [beta.html]
+    auth: true
+    auth_mode: status
+  dashboard_post:
+    components: [beta.html]
+    methods: [POST]
+`, map[string]string{
+		"dashboard.html": `dashboard`,
+		"beta.html":      `beta`,
+	}, tiny.AuthInfo(func(ctx context.Context) (interface{}, bool) {
+		return nil, false
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	r.AddCookie(&http.Cookie{Name: "bucket", Value: "beta"})
+	if rw := serve(site, r); rw.Code != http.StatusUnauthorized || rw.Body.String() == "beta" {
+		t.Errorf("got status=%d, body=%s, want status=401 for auth variant", rw.Code, rw.Body.String())
+	}
+	r = httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	r.Header.Set("X-Post", "1")
+	if rw := serve(site, r); rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status=%d, want status=405 for variant not accepting GET", rw.Code)
+	}
+	if rw := serve(site, httptest.NewRequest(http.MethodGet, "/dashboard", nil)); rw.Body.String() != "dashboard" {
+		t.Errorf("got body=%s, want body=dashboard", rw.Body.String())
+	}
+}
+
+func TestInvalidPageVariant(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("got no panic, want panic for variant without header or cookie")
+		}
+	}()
+	newTestSite(t, `
+pages:
+  pricing:
+    path: /pricing
+    components: [pricing.html]
+    variants:
+      - page: pricing_b
+  pricing_b:
+    components: [pricing.html]
+`, map[string]string{
+		"pricing.html": `pricing`,
+	})
+}
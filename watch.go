@@ -0,0 +1,124 @@
+package tiny
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// watchConfigDelay is the delay before reloading the config after it's changed,
+	// editors usually write a file in several operations.
+	watchConfigDelay = 100 * time.Millisecond
+)
+
+// WatchConfig watch the config file for changes and reload the site when it's changed.
+// If the new config is invalid, the error is logged and the current config is kept.
+// Use Close to stop watching.
+func (site *Site) WatchConfig() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	pth, err := filepath.Abs(site.path)
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+	// watch the directory instead of the file, so that the file can be replaced.
+	if err := watcher.Add(filepath.Dir(pth)); err != nil {
+		watcher.Close()
+		return err
+	}
+	stop := site.getStopWatch()
+	go func() {
+		defer watcher.Close()
+		timer := time.NewTimer(watchConfigDelay)
+		timer.Stop()
+		defer timer.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != pth || ev.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				timer.Reset(watchConfigDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				site.logger.Errorf("watch config: %s, err: %v", site.path, err)
+			case <-timer.C:
+				site.reloadConfig()
+			}
+		}
+	}()
+	return nil
+}
+
+// pollConfig check the config file every interval and reload the site when it's modified.
+func (site *Site) pollConfig(interval time.Duration) {
+	modTime := func() time.Time {
+		fi, err := os.Stat(site.path)
+		if err != nil {
+			return time.Time{}
+		}
+		return fi.ModTime()
+	}
+	last := modTime()
+	stop := site.getStopWatch()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				t := modTime()
+				if t.IsZero() || t.Equal(last) {
+					continue
+				}
+				last = t
+				site.reloadConfig()
+			}
+		}
+	}()
+}
+
+// reloadConfig reload the config and log the result.
+func (site *Site) reloadConfig() {
+	if err := site.ReloadConfig(); err != nil {
+		site.logger.Errorf("reload config: %s, keep the current config, err: %v", site.path, err)
+		return
+	}
+	site.logger.Infof("reloaded config: %s", site.path)
+}
+
+// getStopWatch return the channel to be closed when the watchers should stop.
+func (site *Site) getStopWatch() chan struct{} {
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	if site.stopWatch == nil {
+		site.stopWatch = make(chan struct{})
+	}
+	return site.stopWatch
+}
+
+// Close stop watching the config file if it's enabled.
+func (site *Site) Close() error {
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	if site.stopWatch != nil {
+		close(site.stopWatch)
+		site.stopWatch = nil
+	}
+	return nil
+}
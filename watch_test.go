@@ -0,0 +1,62 @@
+package tiny_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pthethanh/tiny"
+)
+
+func TestWatchConfig(t *testing.T) {
+	config := `
+pages:
+  index:
+    path: /
+    components: [index.html]
+  404:
+    path: /404
+    components: [404.html]
+`
+	files := map[string]string{
+		"index.html": `index`,
+		"about.html": `about`,
+		"404.html":   `not found`,
+	}
+	site := newTestSite(t, config, files, tiny.WatchConfig())
+	defer site.Close()
+	get := func(pth string) string {
+		return serve(site, httptest.NewRequest(http.MethodGet, pth, nil)).Body.String()
+	}
+	waitFor := func(pth, body string) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for get(pth) != body {
+			if time.Now().After(deadline) {
+				t.Fatalf("path: %s, got body=%s, want body=%s", pth, get(pth), body)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	if got := get("/about"); got != "not found" {
+		t.Fatalf("got body=%s, want body=not found", got)
+	}
+	if err := os.WriteFile("index.yml", []byte(config+`
+  about:
+    path: /about
+    components: [about.html]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("/about", "about")
+	// invalid config is logged and the current config is kept.
+	if err := os.WriteFile("index.yml", []byte("pages: [invalid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if got := get("/about"); got != "about" {
+		t.Errorf("got body=%s, want body=about", got)
+	}
+}